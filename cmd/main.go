@@ -18,7 +18,14 @@ func main() {
 	config := config.New()
 	logger := logger.New()
 
-	srv := webserver.New(config, logger)
+	srv, err := webserver.New(config, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msgf("error while creating server: %v", err)
+	}
+
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	srv.StartWorkers(workers)
 
 	go func() {
 		logger.Info().Msgf("server runnning at %s:%s", config.Host, config.Port)
@@ -36,4 +43,10 @@ func main() {
 	if err := srv.Web.Shutdown(context.Background()); err != nil {
 		logger.Fatal().Err(err).Msg("couldnt stop server")
 	}
+	// workers search too, the archive is closed once the last one returned
+	stopWorkers()
+	srv.WaitWorkers()
+	if err := srv.Close(); err != nil {
+		logger.Err(err).Msg("couldnt close server storage")
+	}
 }
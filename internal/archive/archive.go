@@ -0,0 +1,236 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/torrent-api-go/internal/providers"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	queueSize    = 256
+	writeTimeout = 30 * time.Second
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS searches (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	query TEXT NOT NULL,
+	provider TEXT NOT NULL DEFAULT '',
+	filters TEXT NOT NULL,
+	providers TEXT NOT NULL,
+	results INTEGER NOT NULL,
+	started_at INTEGER NOT NULL,
+	duration_ms INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS searches_started_at ON searches (started_at);
+CREATE TABLE IF NOT EXISTS torrents (
+	info_hash TEXT PRIMARY KEY,
+	title TEXT NOT NULL,
+	original_title TEXT NOT NULL,
+	provider TEXT NOT NULL,
+	seeds INTEGER NOT NULL,
+	peers INTEGER NOT NULL,
+	data TEXT NOT NULL,
+	first_seen INTEGER NOT NULL,
+	last_seen INTEGER NOT NULL,
+	times_seen INTEGER NOT NULL DEFAULT 1
+);
+CREATE INDEX IF NOT EXISTS torrents_last_seen ON torrents (last_seen);
+CREATE TABLE IF NOT EXISTS torrent_seeds (
+	info_hash TEXT NOT NULL,
+	provider TEXT NOT NULL,
+	seeds INTEGER NOT NULL,
+	peers INTEGER NOT NULL,
+	seen_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS torrent_seeds_hash ON torrent_seeds (info_hash, seen_at);
+CREATE TABLE IF NOT EXISTS search_results (
+	search_id INTEGER NOT NULL,
+	info_hash TEXT NOT NULL,
+	rank INTEGER NOT NULL,
+	PRIMARY KEY (search_id, info_hash)
+);
+`
+
+// Archive stores every search and the torrents it found in sqlite. Searches
+// are queued by ObserveSearch and written by a single goroutine so they never
+// slow down a response.
+type Archive struct {
+	db      *sql.DB
+	logger  *zerolog.Logger
+	records chan *providers.SearchRecord
+	done    chan struct{}
+	// closed guards records, searches finishing after Close are dropped
+	mu     sync.RWMutex
+	closed bool
+}
+
+func Open(file string, logger *zerolog.Logger) (*Archive, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", file+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("archive %s: %w", file, err)
+	}
+
+	a := &Archive{
+		db:      db,
+		logger:  logger,
+		records: make(chan *providers.SearchRecord, queueSize),
+		done:    make(chan struct{}),
+	}
+	go a.run()
+	return a, nil
+}
+
+// ObserveSearch queues the search for writing, it is dropped when the queue
+// is full or the archive is closed.
+func (a *Archive) ObserveSearch(record *providers.SearchRecord) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return
+	}
+	select {
+	case a.records <- record:
+	default:
+		a.logger.Warn().Msgf("archive queue is full, dropping search %s", record.Params.Query)
+	}
+}
+
+// Close writes the queued searches and closes the database.
+func (a *Archive) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.records)
+	a.mu.Unlock()
+	<-a.done
+	return a.db.Close()
+}
+
+func (a *Archive) run() {
+	defer close(a.done)
+	for record := range a.records {
+		if err := a.write(record); err != nil {
+			a.logger.Err(err).Msgf("error while archiving search %s: %v", record.Params.Query, err)
+		}
+	}
+}
+
+func (a *Archive) write(record *providers.SearchRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	query, err := url.PathUnescape(record.Params.Query)
+	if err != nil {
+		query = record.Params.Query
+	}
+	filters, err := json.Marshal(searchFilters(record.Params))
+	if err != nil {
+		return err
+	}
+	statuses, err := json.Marshal(record.Providers)
+	if err != nil {
+		return err
+	}
+
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO searches (query, provider, filters, providers, results, started_at, duration_ms) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		query, record.Provider, string(filters), string(statuses), len(record.Torrents), record.StartedAt.UnixMilli(), record.Duration.Milliseconds())
+	if err != nil {
+		return err
+	}
+	searchId, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	// cached providers answer with the seeds of an earlier search, they only
+	// refresh last_seen
+	cached := make(map[string]bool)
+	for _, status := range record.Providers {
+		cached[status.Provider] = status.Cached
+	}
+	// every torrent a provider answered is archived with its own seeds, the
+	// results only rank what the search showed
+	seen := record.StartedAt.UnixMilli()
+	for _, torrent := range record.Seen {
+		hash := infoHash(torrent)
+		if hash == "" {
+			continue
+		}
+		if err := a.writeTorrent(ctx, tx, hash, torrent, seen, cached[torrent.Provider]); err != nil {
+			return err
+		}
+	}
+	for rank, torrent := range record.Torrents {
+		hash := infoHash(torrent)
+		if hash == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO search_results (search_id, info_hash, rank) VALUES (?, ?, ?)`, searchId, hash, rank+1); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (a *Archive) writeTorrent(ctx context.Context, tx *sql.Tx, hash string, torrent *providers.Torrent, seen int64, cached bool) error {
+	data, err := json.Marshal(torrent)
+	if err != nil {
+		return err
+	}
+	if cached {
+		res, err := tx.ExecContext(ctx, `UPDATE torrents SET last_seen = max(last_seen, ?), times_seen = times_seen + 1 WHERE info_hash = ?`, seen, hash)
+		if err != nil {
+			return err
+		}
+		if updated, err := res.RowsAffected(); err != nil || updated > 0 {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO torrents (info_hash, title, original_title, provider, seeds, peers, data, first_seen, last_seen) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (info_hash) DO UPDATE SET title = excluded.title, original_title = excluded.original_title, provider = excluded.provider,
+		seeds = excluded.seeds, peers = excluded.peers, data = excluded.data, last_seen = max(last_seen, excluded.last_seen), times_seen = times_seen + 1`,
+		hash, torrent.Title, torrent.OriginalTitle, torrent.Provider, torrent.Seeds, torrent.Peers, string(data), seen, seen)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO torrent_seeds (info_hash, provider, seeds, peers, seen_at) VALUES (?, ?, ?, ?, ?)`,
+		hash, torrent.Provider, torrent.Seeds, torrent.Peers, seen)
+	return err
+}
+
+func infoHash(torrent *providers.Torrent) string {
+	if torrent.InfoHash != "" {
+		return strings.ToLower(torrent.InfoHash)
+	}
+	return strings.ToLower(providers.InfoHashFromMagnet(torrent.Magnet))
+}
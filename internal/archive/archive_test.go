@@ -0,0 +1,89 @@
+package archive
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/torrent-api-go/internal/providers"
+)
+
+func TestObserveSearchAfterClose(t *testing.T) {
+	logger := zerolog.Nop()
+	file := filepath.Join(t.TempDir(), "archive.db")
+	a, err := Open(file, &logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := &providers.SearchRecord{
+		Params:    providers.SearchParams{Query: "matrix"},
+		StartedAt: time.Now(),
+		Torrents:  []*providers.Torrent{{Provider: "test", Title: "matrix", InfoHash: "ABC"}},
+		Seen:      []*providers.Torrent{{Provider: "test", Title: "matrix", InfoHash: "ABC"}},
+	}
+	a.ObserveSearch(record)
+	if err := a.Close(); err != nil {
+		t.Fatalf("error while closing: %v", err)
+	}
+
+	// late searches of stopping workers are dropped instead of panicking
+	a.ObserveSearch(record)
+	if err := a.Close(); err != nil {
+		t.Fatalf("error while closing twice: %v", err)
+	}
+
+	reopened, err := Open(file, &logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	torrent, err := reopened.Torrent(context.Background(), "abc")
+	if err != nil || torrent == nil {
+		t.Fatalf("expected the queued search to be written on close, got %v", err)
+	}
+}
+
+func TestArchiveKeepsEveryTorrentSeen(t *testing.T) {
+	logger := zerolog.Nop()
+	a, err := Open(filepath.Join(t.TempDir(), "archive.db"), &logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	ranked := &providers.Torrent{Provider: "yts", Title: "matrix", InfoHash: "aaa", Seeds: 20, Sources: []string{"yts", "tpb"}}
+	err = a.write(&providers.SearchRecord{
+		Params:    providers.SearchParams{Query: "matrix"},
+		StartedAt: time.Now(),
+		Torrents:  []*providers.Torrent{ranked},
+		Seen: []*providers.Torrent{
+			{Provider: "yts", Title: "matrix", InfoHash: "aaa", Seeds: 10},
+			{Provider: "tpb", Title: "matrix", InfoHash: "AAA", Seeds: 20},
+			// dropped by the filters, still seen
+			{Provider: "tpb", Title: "matrix cam", Magnet: "magnet:?xt=urn:btih:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", Seeds: 3},
+		},
+	})
+	if err != nil {
+		t.Fatalf("error while archiving: %v", err)
+	}
+
+	torrent, err := a.Torrent(context.Background(), "aaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(torrent.History) != 2 {
+		t.Fatalf("expected the seeds of both providers, got %d samples", len(torrent.History))
+	}
+	if _, err := a.Torrent(context.Background(), "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"); err != nil {
+		t.Fatalf("expected the filtered torrent to be archived, got %v", err)
+	}
+	search, err := a.Search(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(search.Torrents) != 1 || !strings.EqualFold(search.Torrents[0].InfoHash, "aaa") {
+		t.Fatalf("expected only the ranked torrent in the results, got %d", len(search.Torrents))
+	}
+}
@@ -0,0 +1,281 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xochilpili/torrent-api-go/internal/providers"
+)
+
+var ErrNotFound = errors.New("not found in archive")
+
+type Filters struct {
+	Title      string `json:"title,omitempty"`
+	Resolution string `json:"resolution,omitempty"`
+	Group      string `json:"group,omitempty"`
+	Season     int    `json:"season,omitempty"`
+	Episode    int    `json:"episode,omitempty"`
+	Profile    string `json:"profile,omitempty"`
+	Sort       string `json:"sort,omitempty"`
+	Order      string `json:"order,omitempty"`
+}
+
+type Search struct {
+	Id         int64                       `json:"id"`
+	Query      string                      `json:"query"`
+	Provider   string                      `json:"provider,omitempty"`
+	Filters    Filters                     `json:"filters"`
+	Providers  []*providers.ProviderStatus `json:"providers"`
+	Results    int                         `json:"results"`
+	StartedAt  time.Time                   `json:"started_at"`
+	DurationMs int64                       `json:"duration_ms"`
+	Torrents   []*Torrent                  `json:"data,omitempty"`
+}
+
+type SeedSample struct {
+	Provider string    `json:"provider"`
+	Seeds    int       `json:"seeds"`
+	Peers    int       `json:"peers"`
+	SeenAt   time.Time `json:"seen_at"`
+}
+
+// Torrent is the last known version of a torrent with its archive details.
+type Torrent struct {
+	*providers.Torrent
+	FirstSeen time.Time     `json:"first_seen"`
+	LastSeen  time.Time     `json:"last_seen"`
+	TimesSeen int           `json:"times_seen"`
+	History   []*SeedSample `json:"seed_history,omitempty"`
+}
+
+// Query narrows a listing, Term matches the search query or the torrent title.
+type Query struct {
+	Term     string
+	Provider string
+	Since    time.Time
+	Limit    int
+	Offset   int
+}
+
+func searchFilters(params providers.SearchParams) Filters {
+	return Filters{
+		Title:      params.Filters.Title,
+		Resolution: params.Filters.Resolution,
+		Group:      params.Filters.Group,
+		Season:     params.Filters.Season,
+		Episode:    params.Filters.Episode,
+		Profile:    params.Profile,
+		Sort:       params.Sort,
+		Order:      params.Order,
+	}
+}
+
+func likeTerm(term string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(term) + "%"
+}
+
+// Searches lists the archived searches newest first with the total count.
+func (a *Archive) Searches(ctx context.Context, query Query) ([]*Search, int, error) {
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if query.Term != "" {
+		where = append(where, `query LIKE ? ESCAPE '\'`)
+		args = append(args, likeTerm(query.Term))
+	}
+	if query.Provider != "" {
+		where = append(where, "provider = ?")
+		args = append(args, query.Provider)
+	}
+	if !query.Since.IsZero() {
+		where = append(where, "started_at >= ?")
+		args = append(args, query.Since.UnixMilli())
+	}
+	condition := strings.Join(where, " AND ")
+
+	var total int
+	if err := a.db.QueryRowContext(ctx, "SELECT count(*) FROM searches WHERE "+condition, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := a.db.QueryContext(ctx, `SELECT id, query, provider, filters, providers, results, started_at, duration_ms FROM searches WHERE `+condition+
+		` ORDER BY started_at DESC, id DESC LIMIT ? OFFSET ?`, append(args, query.Limit, query.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	searches := []*Search{}
+	for rows.Next() {
+		search, err := scanSearch(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		searches = append(searches, search)
+	}
+	return searches, total, rows.Err()
+}
+
+// Search returns an archived search with the torrents it found in rank order.
+func (a *Archive) Search(ctx context.Context, id int64) (*Search, error) {
+	row := a.db.QueryRowContext(ctx, `SELECT id, query, provider, filters, providers, results, started_at, duration_ms FROM searches WHERE id = ?`, id)
+	search, err := scanSearch(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: search %d", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := a.db.QueryContext(ctx, `SELECT t.data, t.first_seen, t.last_seen, t.times_seen FROM search_results r
+		JOIN torrents t ON t.info_hash = r.info_hash WHERE r.search_id = ? ORDER BY r.rank`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	search.Torrents = []*Torrent{}
+	for rows.Next() {
+		torrent, err := scanTorrent(rows)
+		if err != nil {
+			return nil, err
+		}
+		search.Torrents = append(search.Torrents, torrent)
+	}
+	return search, rows.Err()
+}
+
+// Torrents lists archived torrents by last seen, newest first.
+func (a *Archive) Torrents(ctx context.Context, query Query) ([]*Torrent, int, error) {
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if query.Term != "" {
+		where = append(where, `(title LIKE ? ESCAPE '\' OR original_title LIKE ? ESCAPE '\')`)
+		args = append(args, likeTerm(query.Term), likeTerm(query.Term))
+	}
+	if query.Provider != "" {
+		where = append(where, "provider = ?")
+		args = append(args, query.Provider)
+	}
+	if !query.Since.IsZero() {
+		where = append(where, "last_seen >= ?")
+		args = append(args, query.Since.UnixMilli())
+	}
+	condition := strings.Join(where, " AND ")
+
+	var total int
+	if err := a.db.QueryRowContext(ctx, "SELECT count(*) FROM torrents WHERE "+condition, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := a.db.QueryContext(ctx, `SELECT data, first_seen, last_seen, times_seen FROM torrents WHERE `+condition+
+		` ORDER BY last_seen DESC LIMIT ? OFFSET ?`, append(args, query.Limit, query.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	torrents := []*Torrent{}
+	for rows.Next() {
+		torrent, err := scanTorrent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		torrents = append(torrents, torrent)
+	}
+	return torrents, total, rows.Err()
+}
+
+// Torrent looks up a torrent by info hash together with its seed history.
+func (a *Archive) Torrent(ctx context.Context, infoHash string) (*Torrent, error) {
+	infoHash = strings.ToLower(infoHash)
+	row := a.db.QueryRowContext(ctx, `SELECT data, first_seen, last_seen, times_seen FROM torrents WHERE info_hash = ?`, infoHash)
+	torrent, err := scanTorrent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: torrent %s", ErrNotFound, infoHash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := a.db.QueryContext(ctx, `SELECT provider, seeds, peers, seen_at FROM torrent_seeds WHERE info_hash = ? ORDER BY seen_at`, infoHash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	torrent.History = []*SeedSample{}
+	for rows.Next() {
+		var sample SeedSample
+		var seenAt int64
+		if err := rows.Scan(&sample.Provider, &sample.Seeds, &sample.Peers, &seenAt); err != nil {
+			return nil, err
+		}
+		sample.SeenAt = time.UnixMilli(seenAt)
+		torrent.History = append(torrent.History, &sample)
+	}
+	return torrent, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSearch(row scanner) (*Search, error) {
+	var search Search
+	var filters, statuses string
+	var startedAt int64
+	if err := row.Scan(&search.Id, &search.Query, &search.Provider, &filters, &statuses, &search.Results, &startedAt, &search.DurationMs); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(filters), &search.Filters); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(statuses), &search.Providers); err != nil {
+		return nil, err
+	}
+	search.StartedAt = time.UnixMilli(startedAt)
+	return &search, nil
+}
+
+func scanTorrent(row scanner) (*Torrent, error) {
+	var data string
+	var firstSeen, lastSeen int64
+	torrent := &Torrent{Torrent: &providers.Torrent{}}
+	if err := row.Scan(&data, &firstSeen, &lastSeen, &torrent.TimesSeen); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(data), torrent.Torrent); err != nil {
+		return nil, err
+	}
+	torrent.FirstSeen = time.UnixMilli(firstSeen)
+	torrent.LastSeen = time.UnixMilli(lastSeen)
+	return torrent, nil
+}
+
+// FirstSeen returns when each of the info hashes was first archived, unknown
+// hashes are left out.
+func (a *Archive) FirstSeen(ctx context.Context, hashes []string) (map[string]time.Time, error) {
+	seen := make(map[string]time.Time)
+	if len(hashes) == 0 {
+		return seen, nil
+	}
+	args := make([]interface{}, 0, len(hashes))
+	for _, hash := range hashes {
+		args = append(args, strings.ToLower(hash))
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+	rows, err := a.db.QueryContext(ctx, `SELECT info_hash, first_seen FROM torrents WHERE info_hash IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hash string
+		var firstSeen int64
+		if err := rows.Scan(&hash, &firstSeen); err != nil {
+			return nil, err
+		}
+		seen[hash] = time.UnixMilli(firstSeen)
+	}
+	return seen, rows.Err()
+}
@@ -0,0 +1,19 @@
+package cache
+
+import "time"
+
+type Entry struct {
+	Value    []byte    `json:"value"`
+	StoredAt time.Time `json:"stored_at"`
+}
+
+func (e *Entry) Age() time.Duration {
+	return time.Since(e.StoredAt)
+}
+
+// Backend stores raw entries, expiration policies are left to the caller.
+type Backend interface {
+	Get(key string) (*Entry, bool)
+	Set(key string, entry *Entry) error
+	Delete(key string) error
+}
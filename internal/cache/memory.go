@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+type lruItem struct {
+	key   string
+	entry *Entry
+}
+
+// Memory is an in-memory LRU backend holding up to size entries.
+type Memory struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+func NewMemory(size int) *Memory {
+	if size <= 0 {
+		size = 1
+	}
+	return &Memory{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (m *Memory) Get(key string) (*Entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	element, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	m.order.MoveToFront(element)
+	return element.Value.(*lruItem).entry, true
+}
+
+func (m *Memory) Set(key string, entry *Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if element, ok := m.items[key]; ok {
+		element.Value.(*lruItem).entry = entry
+		m.order.MoveToFront(element)
+		return nil
+	}
+	m.items[key] = m.order.PushFront(&lruItem{key: key, entry: entry})
+	for m.order.Len() > m.size {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.items, oldest.Value.(*lruItem).key)
+	}
+	return nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if element, ok := m.items[key]; ok {
+		m.order.Remove(element)
+		delete(m.items, key)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -10,8 +11,64 @@ import (
 var EnvPreffix = "TAG"
 
 type Config struct {
-	Host string `default:"0.0.0.0"`
-	Port string `default:"4001"`
+	Host          string        `default:"0.0.0.0"`
+	Port          string        `default:"4001"`
+	ProvidersDir  string        `split_words:"true"`
+	CacheEnabled  bool          `default:"true" split_words:"true"`
+	CacheBackend  string        `default:"memory" split_words:"true"`
+	CacheSize     int           `default:"500" split_words:"true"`
+	CacheDir      string        `default:"./cache" split_words:"true"`
+	CacheTTL      time.Duration `default:"5m" split_words:"true"`
+	CacheStaleTTL time.Duration `default:"30m" split_words:"true"`
+	// providers are probed in the background with a canary search
+	HealthEnabled      bool          `default:"true" split_words:"true"`
+	HealthInterval     time.Duration `default:"5m" split_words:"true"`
+	HealthQuery        string        `default:"matrix" split_words:"true"`
+	HealthHistory      int           `default:"20" split_words:"true"`
+	HealthMinProviders int           `default:"1" split_words:"true"`
+	// providers failing in a row are skipped until a cool-down is over
+	BreakerEnabled     bool          `default:"true" split_words:"true"`
+	BreakerFailures    int           `default:"5" split_words:"true"`
+	BreakerCooldown    time.Duration `default:"1m" split_words:"true"`
+	BreakerMaxCooldown time.Duration `default:"15m" split_words:"true"`
+	// comma separated http(s) or socks5 proxies used by providers without their own
+	Proxies []string
+	// TAG_PROVIDERS_DIR is polled for changes on this interval
+	ProvidersReloadInterval time.Duration `default:"10s" split_words:"true"`
+	// comma separated ips or cidrs of reverse proxies whose X-Forwarded-* headers are honoured
+	TrustedProxies []string `split_words:"true"`
+	// the admin api is disabled while no token is set
+	AdminToken string `split_words:"true"`
+	OverlayDir string `default:"./overlay" split_words:"true"`
+	// download clients are enabled by setting their url
+	DownloadClient       string `split_words:"true"`
+	DownloadCategory     string `split_words:"true"`
+	DownloadSavePath     string `split_words:"true"`
+	QbittorrentUrl       string `split_words:"true"`
+	QbittorrentUsername  string `split_words:"true"`
+	QbittorrentPassword  string `split_words:"true"`
+	TransmissionUrl      string `split_words:"true"`
+	TransmissionUsername string `split_words:"true"`
+	TransmissionPassword string `split_words:"true"`
+	DelugeUrl            string `split_words:"true"`
+	DelugePassword       string `split_words:"true"`
+
+	// watchlist items are checked for new episodes on every interval
+	WatchlistEnabled  bool          `default:"true" split_words:"true"`
+	WatchlistFile     string        `default:"./data/watchlist.json" split_words:"true"`
+	WatchlistInterval time.Duration `default:"30m" split_words:"true"`
+
+	// searches and their torrents are archived in sqlite when enabled
+	ArchiveEnabled bool   `default:"false" split_words:"true"`
+	ArchiveFile    string `default:"./data/archive.db" split_words:"true"`
+
+	// saved searches are re-run on the interval and new releases sent to webhooks
+	WebhooksEnabled  bool          `default:"true" split_words:"true"`
+	WebhooksFile     string        `default:"./data/webhooks.json" split_words:"true"`
+	WebhooksInterval time.Duration `default:"15m" split_words:"true"`
+	WebhooksTimeout  time.Duration `default:"10s" split_words:"true"`
+	WebhooksRetries  int           `default:"4" split_words:"true"`
+	WebhooksBackoff  time.Duration `default:"2s" split_words:"true"`
 }
 
 func New() *Config {
@@ -29,6 +86,13 @@ func Get() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	// tickers panic on intervals that are not positive
+	if cfg.WatchlistEnabled && cfg.WatchlistInterval <= 0 {
+		return nil, fmt.Errorf("TAG_WATCHLIST_INTERVAL must be positive, got %s", cfg.WatchlistInterval)
+	}
+	if cfg.WebhooksEnabled && cfg.WebhooksInterval <= 0 {
+		return nil, fmt.Errorf("TAG_WEBHOOKS_INTERVAL must be positive, got %s", cfg.WebhooksInterval)
+	}
 
 	return &cfg, nil
 }
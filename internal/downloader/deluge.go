@@ -0,0 +1,133 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// deluge answers this code when the web session is not authenticated
+const delugeNotAuthenticated = 1
+
+// Deluge talks to the Deluge WebUI json-rpc endpoint, usually
+// http://host:8112/json, the web ui has to be connected to a daemon.
+type Deluge struct {
+	rs       *resty.Client
+	url      string
+	password string
+	mu       sync.Mutex
+	ids      uint64
+}
+
+type delugeRequest struct {
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+	Id     uint64        `json:"id"`
+}
+
+type delugeError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+func (e *delugeError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+type delugeResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *delugeError    `json:"error"`
+}
+
+func NewDeluge(url string, password string) *Deluge {
+	return &Deluge{rs: resty.New(), url: url, password: password}
+}
+
+func (d *Deluge) Name() string {
+	return "deluge"
+}
+
+func (d *Deluge) call(ctx context.Context, method string, params ...interface{}) (json.RawMessage, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	resp, err := d.rs.R().SetContext(ctx).
+		SetBody(&delugeRequest{Method: method, Params: params, Id: atomic.AddUint64(&d.ids, 1)}).
+		Post(d.url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("%s failed with status %d", method, resp.StatusCode())
+	}
+	var result delugeResponse
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return nil, fmt.Errorf("invalid %s response: %w", method, err)
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return result.Result, nil
+}
+
+func (d *Deluge) login(ctx context.Context) error {
+	result, err := d.call(ctx, "auth.login", d.password)
+	if err != nil {
+		return err
+	}
+	var ok bool
+	if err := json.Unmarshal(result, &ok); err != nil || !ok {
+		return errors.New("login failed")
+	}
+	return nil
+}
+
+// authenticated runs a call logging in again when the session is gone.
+func (d *Deluge) authenticated(ctx context.Context, method string, params ...interface{}) (json.RawMessage, error) {
+	result, err := d.call(ctx, method, params...)
+	var rpcErr *delugeError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != delugeNotAuthenticated {
+		return result, err
+	}
+	if err := d.login(ctx); err != nil {
+		return nil, err
+	}
+	return d.call(ctx, method, params...)
+}
+
+func (d *Deluge) Add(ctx context.Context, request *Request) error {
+	options := map[string]interface{}{"add_paused": request.Paused}
+	if request.SavePath != "" {
+		options["download_location"] = request.SavePath
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	result, err := d.authenticated(ctx, "core.add_torrent_magnet", request.Magnet, options)
+	if err != nil {
+		return err
+	}
+	var torrentId string
+	if err := json.Unmarshal(result, &torrentId); err != nil || torrentId == "" {
+		return errors.New("torrent was not added, it may already exist")
+	}
+	if request.Category == "" {
+		return nil
+	}
+
+	// labels need the label plugin, adding an existing label fails harmlessly
+	label := strings.ToLower(request.Category)
+	if _, err := d.call(ctx, "label.add", label); err != nil && !strings.Contains(err.Error(), "already exists") {
+		return fmt.Errorf("torrent added but labeling failed: %w", err)
+	}
+	if _, err := d.call(ctx, "label.set_torrent", torrentId, label); err != nil {
+		return fmt.Errorf("torrent added but labeling failed: %w", err)
+	}
+	return nil
+}
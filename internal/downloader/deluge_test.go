@@ -0,0 +1,94 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeDeluge struct {
+	loggedIn bool
+	calls    []string
+	// labelError is answered to label.set_torrent when set
+	labelError string
+}
+
+func (f *fakeDeluge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+		Id     uint64            `json:"id"`
+	}
+	json.NewDecoder(r.Body).Decode(&request)
+	f.calls = append(f.calls, request.Method)
+
+	answer := func(result interface{}, err *delugeError) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "error": err, "id": request.Id})
+	}
+	if request.Method == "auth.login" {
+		f.loggedIn = len(request.Params) == 1 && string(request.Params[0]) == `"secret"`
+		answer(f.loggedIn, nil)
+		return
+	}
+	if !f.loggedIn {
+		answer(nil, &delugeError{Message: "Not authenticated", Code: delugeNotAuthenticated})
+		return
+	}
+	switch request.Method {
+	case "core.add_torrent_magnet":
+		answer("torrent-id", nil)
+	case "label.add":
+		answer(nil, &delugeError{Message: "Label already exists", Code: 4})
+	case "label.set_torrent":
+		if f.labelError != "" {
+			answer(nil, &delugeError{Message: f.labelError, Code: 2})
+			return
+		}
+		answer(nil, nil)
+	default:
+		answer(nil, &delugeError{Message: "Unknown method", Code: 2})
+	}
+}
+
+func TestDelugeLoginOnNotAuthenticated(t *testing.T) {
+	fake := &fakeDeluge{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	err := NewDeluge(server.URL, "secret").Add(context.Background(), &Request{Magnet: "magnet:?xt=urn:btih:abc", Category: "TV"})
+	if err != nil {
+		t.Fatalf("error while adding: %v", err)
+	}
+	expected := "core.add_torrent_magnet,auth.login,core.add_torrent_magnet,label.add,label.set_torrent"
+	if calls := strings.Join(fake.calls, ","); calls != expected {
+		t.Fatalf("expected calls %s, got %s", expected, calls)
+	}
+}
+
+func TestDelugeWrongPassword(t *testing.T) {
+	fake := &fakeDeluge{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	err := NewDeluge(server.URL, "wrong").Add(context.Background(), &Request{Magnet: "magnet:?xt=urn:btih:abc"})
+	if err == nil || err.Error() != "login failed" {
+		t.Fatalf("expected a login error, got %v", err)
+	}
+}
+
+func TestDelugeLabelFailureAfterAdd(t *testing.T) {
+	fake := &fakeDeluge{labelError: "Unknown Label"}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	err := NewDeluge(server.URL, "secret").Add(context.Background(), &Request{Magnet: "magnet:?xt=urn:btih:abc", Category: "tv"})
+	if err == nil || !strings.HasPrefix(err.Error(), "torrent added but labeling failed") {
+		t.Fatalf("expected a labeling error, got %v", err)
+	}
+	if fake.calls[len(fake.calls)-1] != "label.set_torrent" {
+		t.Fatalf("expected labeling to run after the add, got %v", fake.calls)
+	}
+}
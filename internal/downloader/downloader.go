@@ -0,0 +1,115 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/torrent-api-go/internal/config"
+)
+
+const clientTimeout = 15 * time.Second
+
+var (
+	ErrUnknownClient = errors.New("unknown download client")
+	ErrNoClient      = errors.New("no download client configured")
+)
+
+// Request is a torrent to hand to a download client, Category maps to a
+// qBittorrent category, a Transmission label or a Deluge label.
+type Request struct {
+	Magnet   string `json:"magnet"`
+	Category string `json:"category,omitempty"`
+	SavePath string `json:"save_path,omitempty"`
+	Paused   bool   `json:"paused"`
+}
+
+type Client interface {
+	Name() string
+	Add(ctx context.Context, request *Request) error
+}
+
+// Downloader holds every configured download client, a client is configured
+// as soon as its url is set.
+type Downloader struct {
+	logger   *zerolog.Logger
+	clients  map[string]Client
+	fallback string
+	category string
+	savePath string
+}
+
+func New(config *config.Config, logger *zerolog.Logger) (*Downloader, error) {
+	d := &Downloader{
+		logger:   logger,
+		clients:  make(map[string]Client),
+		category: config.DownloadCategory,
+		savePath: config.DownloadSavePath,
+	}
+	if config.QbittorrentUrl != "" {
+		d.clients["qbittorrent"] = NewQbittorrent(config.QbittorrentUrl, config.QbittorrentUsername, config.QbittorrentPassword)
+	}
+	if config.TransmissionUrl != "" {
+		d.clients["transmission"] = NewTransmission(config.TransmissionUrl, config.TransmissionUsername, config.TransmissionPassword)
+	}
+	if config.DelugeUrl != "" {
+		d.clients["deluge"] = NewDeluge(config.DelugeUrl, config.DelugePassword)
+	}
+
+	d.fallback = config.DownloadClient
+	if d.fallback == "" && len(d.clients) == 1 {
+		for name := range d.clients {
+			d.fallback = name
+		}
+	}
+	if d.fallback != "" {
+		if _, ok := d.clients[d.fallback]; !ok {
+			return nil, fmt.Errorf("%w: default client %s has no url configured", ErrUnknownClient, d.fallback)
+		}
+	}
+	return d, nil
+}
+
+func (d *Downloader) Clients() []string {
+	var names []string
+	for name := range d.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Add sends the request to the named client, an empty name uses the default
+// client. Missing category and save path fall back to the configured ones.
+func (d *Downloader) Add(ctx context.Context, name string, request *Request) (string, error) {
+	if name == "" {
+		name = d.fallback
+	}
+	if name == "" {
+		if len(d.clients) == 0 {
+			return "", ErrNoClient
+		}
+		return "", fmt.Errorf("%w: several clients are configured, pick one of %v", ErrUnknownClient, d.Clients())
+	}
+	client, ok := d.clients[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownClient, name)
+	}
+	if request.Category == "" {
+		request.Category = d.category
+	}
+	if request.SavePath == "" {
+		request.SavePath = d.savePath
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, clientTimeout)
+	defer cancel()
+	if err := client.Add(ctx, request); err != nil {
+		return name, fmt.Errorf("%s: %w", name, err)
+	}
+	d.logger.Info().Msgf("sent torrent to %s, category: %s, paused: %t", name, request.Category, request.Paused)
+	return name, nil
+}
@@ -0,0 +1,89 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Qbittorrent talks to the qBittorrent WebUI api, the session cookie is kept
+// by the resty cookie jar and renewed when it expires.
+type Qbittorrent struct {
+	rs       *resty.Client
+	username string
+	password string
+	mu       sync.Mutex
+	loggedIn bool
+}
+
+func NewQbittorrent(baseUrl string, username string, password string) *Qbittorrent {
+	return &Qbittorrent{
+		rs:       resty.New().SetBaseURL(strings.TrimRight(baseUrl, "/")),
+		username: username,
+		password: password,
+	}
+}
+
+func (q *Qbittorrent) Name() string {
+	return "qbittorrent"
+}
+
+func (q *Qbittorrent) login(ctx context.Context) error {
+	resp, err := q.rs.R().SetContext(ctx).
+		SetFormData(map[string]string{"username": q.username, "password": q.password}).
+		Post("/api/v2/auth/login")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() || strings.TrimSpace(resp.String()) != "Ok." {
+		return fmt.Errorf("login failed with status %d: %s", resp.StatusCode(), strings.TrimSpace(resp.String()))
+	}
+	q.loggedIn = true
+	return nil
+}
+
+func (q *Qbittorrent) Add(ctx context.Context, request *Request) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.loggedIn {
+		if err := q.login(ctx); err != nil {
+			return err
+		}
+	}
+	resp, err := q.add(ctx, request)
+	if err == nil && resp.StatusCode() == http.StatusForbidden {
+		// the session expired
+		if err := q.login(ctx); err != nil {
+			return err
+		}
+		resp, err = q.add(ctx, request)
+	}
+	if err != nil {
+		return err
+	}
+	if resp.IsError() || strings.TrimSpace(resp.String()) == "Fails." {
+		return fmt.Errorf("add failed with status %d: %s", resp.StatusCode(), strings.TrimSpace(resp.String()))
+	}
+	return nil
+}
+
+func (q *Qbittorrent) add(ctx context.Context, request *Request) (*resty.Response, error) {
+	form := map[string]string{
+		"urls": request.Magnet,
+		// qBittorrent 5 renamed paused to stopped
+		"paused":  strconv.FormatBool(request.Paused),
+		"stopped": strconv.FormatBool(request.Paused),
+	}
+	if request.Category != "" {
+		form["category"] = request.Category
+	}
+	if request.SavePath != "" {
+		form["savepath"] = request.SavePath
+	}
+	return q.rs.R().SetContext(ctx).SetFormData(form).Post("/api/v2/torrents/add")
+}
@@ -0,0 +1,103 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeQbittorrent struct {
+	logins      int
+	adds        int
+	loginAnswer string
+	expireFirst bool
+	form        map[string]string
+}
+
+func (f *fakeQbittorrent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	switch r.URL.Path {
+	case "/api/v2/auth/login":
+		f.logins++
+		if r.FormValue("username") != "admin" || r.FormValue("password") != "secret" {
+			w.Write([]byte("Fails."))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "SID", Value: "session", Path: "/"})
+		w.Write([]byte(f.loginAnswer))
+	case "/api/v2/torrents/add":
+		f.adds++
+		if cookie, err := r.Cookie("SID"); err != nil || cookie.Value != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if f.expireFirst && f.adds == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		f.form = map[string]string{}
+		for key := range r.PostForm {
+			f.form[key] = r.PostForm.Get(key)
+		}
+		w.Write([]byte("Ok."))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestQbittorrentLogin(t *testing.T) {
+	fake := &fakeQbittorrent{loginAnswer: "Ok."}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	err := NewQbittorrent(server.URL, "admin", "wrong").Add(context.Background(), &Request{Magnet: "magnet:?xt=urn:btih:abc"})
+	if err == nil || !strings.Contains(err.Error(), "login failed") {
+		t.Fatalf("expected a login error for a Fails. answer, got %v", err)
+	}
+	if fake.adds != 0 {
+		t.Fatalf("expected no add after a failed login, got %d", fake.adds)
+	}
+
+	client := NewQbittorrent(server.URL+"/", "admin", "secret")
+	for i := 0; i < 2; i++ {
+		if err := client.Add(context.Background(), &Request{Magnet: "magnet:?xt=urn:btih:abc"}); err != nil {
+			t.Fatalf("error while adding: %v", err)
+		}
+	}
+	if fake.logins != 2 {
+		t.Fatalf("expected the session to be reused after the first login, got %d logins", fake.logins)
+	}
+}
+
+func TestQbittorrentLoginAgainOnForbidden(t *testing.T) {
+	fake := &fakeQbittorrent{loginAnswer: "Ok.", expireFirst: true}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	err := NewQbittorrent(server.URL, "admin", "secret").Add(context.Background(), &Request{
+		Magnet:   "magnet:?xt=urn:btih:abc",
+		Category: "tv",
+		SavePath: "/downloads/tv",
+		Paused:   true,
+	})
+	if err != nil {
+		t.Fatalf("error while adding: %v", err)
+	}
+	if fake.logins != 2 || fake.adds != 2 {
+		t.Fatalf("expected a login and a retry after the 403, got %d logins and %d adds", fake.logins, fake.adds)
+	}
+	expected := map[string]string{
+		"urls":     "magnet:?xt=urn:btih:abc",
+		"category": "tv",
+		"savepath": "/downloads/tv",
+		"paused":   "true",
+		"stopped":  "true",
+	}
+	for key, value := range expected {
+		if fake.form[key] != value {
+			t.Errorf("expected form %s=%q, got %q", key, value, fake.form[key])
+		}
+	}
+}
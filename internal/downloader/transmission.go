@@ -0,0 +1,86 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const transmissionSessionHeader = "X-Transmission-Session-Id"
+
+// Transmission talks to the Transmission rpc endpoint, usually
+// http://host:9091/transmission/rpc, answering the csrf session handshake.
+type Transmission struct {
+	rs        *resty.Client
+	url       string
+	mu        sync.Mutex
+	sessionId string
+}
+
+type transmissionRequest struct {
+	Method    string                 `json:"method"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+type transmissionResponse struct {
+	Result string `json:"result"`
+}
+
+func NewTransmission(url string, username string, password string) *Transmission {
+	rs := resty.New()
+	if username != "" {
+		rs.SetBasicAuth(username, password)
+	}
+	return &Transmission{rs: rs, url: url}
+}
+
+func (t *Transmission) Name() string {
+	return "transmission"
+}
+
+func (t *Transmission) Add(ctx context.Context, request *Request) error {
+	arguments := map[string]interface{}{
+		"filename": request.Magnet,
+		"paused":   request.Paused,
+	}
+	if request.SavePath != "" {
+		arguments["download-dir"] = request.SavePath
+	}
+	if request.Category != "" {
+		arguments["labels"] = []string{request.Category}
+	}
+	body := &transmissionRequest{Method: "torrent-add", Arguments: arguments}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	resp, err := t.post(ctx, body)
+	if err == nil && resp.StatusCode() == http.StatusConflict {
+		t.sessionId = resp.Header().Get(transmissionSessionHeader)
+		resp, err = t.post(ctx, body)
+	}
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return fmt.Errorf("rpc failed with status %d", resp.StatusCode())
+	}
+	var result transmissionResponse
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return fmt.Errorf("invalid rpc response: %w", err)
+	}
+	if result.Result != "success" {
+		return fmt.Errorf("rpc failed: %s", result.Result)
+	}
+	return nil
+}
+
+func (t *Transmission) post(ctx context.Context, body *transmissionRequest) (*resty.Response, error) {
+	return t.rs.R().SetContext(ctx).
+		SetHeader(transmissionSessionHeader, t.sessionId).
+		SetBody(body).
+		Post(t.url)
+}
@@ -0,0 +1,71 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeTransmission struct {
+	requests  int
+	sessionId string
+	result    string
+	body      transmissionRequest
+}
+
+func (f *fakeTransmission) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests++
+	if r.Header.Get(transmissionSessionHeader) != f.sessionId {
+		w.Header().Set(transmissionSessionHeader, f.sessionId)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	json.NewDecoder(r.Body).Decode(&f.body)
+	w.Write([]byte(`{"result":"` + f.result + `","arguments":{}}`))
+}
+
+func TestTransmissionSessionHandshake(t *testing.T) {
+	fake := &fakeTransmission{sessionId: "first", result: "success"}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := NewTransmission(server.URL, "", "")
+	err := client.Add(context.Background(), &Request{Magnet: "magnet:?xt=urn:btih:abc", Category: "movies", SavePath: "/downloads"})
+	if err != nil {
+		t.Fatalf("error while adding: %v", err)
+	}
+	if fake.requests != 2 {
+		t.Fatalf("expected the 409 to be answered with a retry, got %d requests", fake.requests)
+	}
+	if fake.body.Method != "torrent-add" || fake.body.Arguments["filename"] != "magnet:?xt=urn:btih:abc" || fake.body.Arguments["download-dir"] != "/downloads" {
+		t.Fatalf("unexpected rpc request: %+v", fake.body)
+	}
+
+	// the session id is kept until the daemon rotates it
+	if err := client.Add(context.Background(), &Request{Magnet: "magnet:?xt=urn:btih:abc"}); err != nil {
+		t.Fatalf("error while adding: %v", err)
+	}
+	if fake.requests != 3 {
+		t.Fatalf("expected the session id to be reused, got %d requests", fake.requests)
+	}
+	fake.sessionId = "second"
+	if err := client.Add(context.Background(), &Request{Magnet: "magnet:?xt=urn:btih:abc"}); err != nil {
+		t.Fatalf("error while adding after the session rotated: %v", err)
+	}
+	if fake.requests != 5 {
+		t.Fatalf("expected a new handshake after the session rotated, got %d requests", fake.requests)
+	}
+}
+
+func TestTransmissionRpcFailure(t *testing.T) {
+	fake := &fakeTransmission{sessionId: "first", result: "invalid or corrupt torrent file"}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	err := NewTransmission(server.URL, "", "").Add(context.Background(), &Request{Magnet: "magnet:?xt=urn:btih:abc"})
+	if err == nil || err.Error() != "rpc failed: invalid or corrupt torrent file" {
+		t.Fatalf("expected the rpc result as error, got %v", err)
+	}
+}
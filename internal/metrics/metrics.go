@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "torrent_api"
+
+var (
+	ProviderSearches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_searches_total",
+		Help:      "Searches sent to each provider, cached answers included.",
+	}, []string{"provider", "cached"})
+
+	ProviderErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_errors_total",
+		Help:      "Provider searches that failed, by status (error or timeout).",
+	}, []string{"provider", "status"})
+
+	ProviderLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_search_duration_seconds",
+		Help:      "Time taken by each provider to answer a search.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60},
+	}, []string{"provider"})
+
+	ProviderHttpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_http_requests_total",
+		Help:      "Upstream http requests made by providers, by status code (0 on connection errors).",
+	}, []string{"provider", "code"})
+
+	Results = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "results_total",
+		Help:      "Torrents received from providers before postFilter (received) and kept after it (kept).",
+	}, []string{"provider", "stage"})
+
+	FilterDrops = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "filter_dropped_total",
+		Help:      "Torrents dropped by postFilter, by reason.",
+	}, []string{"reason"})
+
+	HttpLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the api http handlers.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,159 @@
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/xochilpili/torrent-api-go/internal/persist"
+)
+
+// ProviderInfo is the admin view of a provider, Sources lists the config
+// files merged into Config from the lowest precedence up.
+type ProviderInfo struct {
+	File    string          `json:"file"`
+	Sources []string        `json:"sources"`
+	Config  *ProviderConfig `json:"config"`
+	Health  *ProviderHealth `json:"health"`
+}
+
+func (p *TorrentManager) providerInfo(set *providerSet, file string) *ProviderInfo {
+	cfg := *set.byFile[file]
+	// proxy urls may carry credentials
+	if cfg.Proxy != nil {
+		proxy := *cfg.Proxy
+		proxy.Urls = nil
+		for _, raw := range cfg.Proxy.Urls {
+			if proxyUrl, err := url.Parse(raw); err == nil {
+				raw = proxyUrl.Redacted()
+			}
+			proxy.Urls = append(proxy.Urls, raw)
+		}
+		cfg.Proxy = &proxy
+	}
+	return &ProviderInfo{
+		File:    file,
+		Sources: set.sources[file],
+		Config:  &cfg,
+		Health:  p.providerHealth(cfg.Name),
+	}
+}
+
+// AdminProviders lists every provider, disabled ones included, sorted by file.
+func (p *TorrentManager) AdminProviders() []*ProviderInfo {
+	set := p.providers()
+	var files []string
+	for file := range set.byFile {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	var providers []*ProviderInfo
+	for _, file := range files {
+		providers = append(providers, p.providerInfo(set, file))
+	}
+	return providers
+}
+
+func (p *TorrentManager) AdminProvider(name string) (*ProviderInfo, error) {
+	set := p.providers()
+	file, cfg := set.lookup(name)
+	if cfg == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return p.providerInfo(set, file), nil
+}
+
+// OverrideProvider merges patch into the overlay file of a provider and
+// reloads the configs, the overlay is rolled back when the result is invalid.
+func (p *TorrentManager) OverrideProvider(name string, patch map[string]interface{}) (*ProviderInfo, error) {
+	if p.config.OverlayDir == "" {
+		return nil, errors.New("no overlay dir configured")
+	}
+	if problems := unknownFields("patch", "", patch, reflect.TypeOf(ProviderConfig{})); len(problems) > 0 {
+		return nil, problems
+	}
+
+	// a reload of the watcher must not read the layers before this write and
+	// store its set after it
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+	file, cfg := p.providers().lookup(name)
+	if cfg == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+
+	overlayFile := filepath.Join(p.config.OverlayDir, file+".json")
+	previous, err := os.ReadFile(overlayFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	overlay := make(map[string]interface{})
+	if previous != nil {
+		if err := json.Unmarshal(previous, &overlay); err != nil {
+			return nil, fmt.Errorf("overlay %s: %w", overlayFile, err)
+		}
+	}
+	data, err := json.MarshalIndent(mergeDocuments(overlay, patch), "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(p.config.OverlayDir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := persist.WriteFile(overlayFile, data); err != nil {
+		return nil, err
+	}
+	if err := p.reload(); err != nil {
+		if rollbackErr := p.restoreOverlay(overlayFile, previous); rollbackErr != nil {
+			p.logger.Err(rollbackErr).Msgf("error while restoring overlay %s: %v", overlayFile, rollbackErr)
+		}
+		return nil, err
+	}
+	p.logger.Info().Msgf("provider %s overridden in %s", name, overlayFile)
+	return p.AdminProvider(file)
+}
+
+// ResetProvider drops the overlay of a provider, bringing back its configured values.
+func (p *TorrentManager) ResetProvider(name string) (*ProviderInfo, error) {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+	file, cfg := p.providers().lookup(name)
+	if cfg == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	overlayFile := filepath.Join(p.config.OverlayDir, file+".json")
+	previous, err := os.ReadFile(overlayFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p.AdminProvider(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(overlayFile); err != nil {
+		return nil, err
+	}
+	if err := p.reload(); err != nil {
+		if rollbackErr := p.restoreOverlay(overlayFile, previous); rollbackErr != nil {
+			p.logger.Err(rollbackErr).Msgf("error while restoring overlay %s: %v", overlayFile, rollbackErr)
+		}
+		return nil, err
+	}
+	return p.AdminProvider(file)
+}
+
+func (p *TorrentManager) restoreOverlay(file string, previous []byte) error {
+	if previous == nil {
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return persist.WriteFile(file, previous)
+}
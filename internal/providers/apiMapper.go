@@ -0,0 +1,237 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ApiMapping describes where torrents live inside a json api response.
+// Field paths are dot separated keys, when TorrentsPath is set they are
+// resolved against the nested torrent entry first and then against its parent result.
+type ApiMapping struct {
+	ResultsPath  string            `json:"resultsPath"`
+	TorrentsPath string            `json:"torrentsPath,omitempty"`
+	Fields       ApiFields         `json:"fields"`
+	Defaults     map[string]string `json:"defaults,omitempty"`
+}
+
+// ApiFields maps each torrent attribute to a json path, Name is parsed as a
+// release name and any other mapped field overrides the parsed values.
+type ApiFields struct {
+	Name          string `json:"name,omitempty"`
+	Title         string `json:"title,omitempty"`
+	OriginalTitle string `json:"originalTitle,omitempty"`
+	InfoHash      string `json:"infoHash,omitempty"`
+	Magnet        string `json:"magnet,omitempty"`
+	Seeds         string `json:"seeds,omitempty"`
+	Peers         string `json:"peers,omitempty"`
+	Size          string `json:"size,omitempty"`
+	SizeBytes     string `json:"sizeBytes,omitempty"`
+	Year          string `json:"year,omitempty"`
+	Resolution    string `json:"resolution,omitempty"`
+	Quality       string `json:"quality,omitempty"`
+	Codec         string `json:"codec,omitempty"`
+	Group         string `json:"group,omitempty"`
+	Type          string `json:"type,omitempty"`
+	Season        string `json:"season,omitempty"`
+	Episode       string `json:"episode,omitempty"`
+}
+
+var titleCleanupRegex = regexp.MustCompile(`\(|\[|\]|\)`)
+
+func (t *TorrentProvider) mapApiResponse(data []byte) ([]*Torrent, error) {
+	mapping := t.config.Api
+	if mapping == nil {
+		return nil, fmt.Errorf("provider %s has no api mapping", t.config.Name)
+	}
+
+	var root interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&root); err != nil {
+		return nil, err
+	}
+
+	results, ok := lookupPath(root, mapping.ResultsPath)
+	if !ok || results == nil {
+		return nil, nil
+	}
+	list, ok := results.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("results path %q is not an array", mapping.ResultsPath)
+	}
+
+	var torrents []*Torrent
+	for _, result := range list {
+		if mapping.TorrentsPath == "" {
+			torrent, err := t.mapApiItem(result, nil)
+			if err != nil {
+				return nil, err
+			}
+			if torrent != nil {
+				torrents = append(torrents, torrent)
+			}
+			continue
+		}
+
+		entries, ok := lookupPath(result, mapping.TorrentsPath)
+		if !ok {
+			continue
+		}
+		nested, ok := entries.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("torrents path %q is not an array", mapping.TorrentsPath)
+		}
+		for _, entry := range nested {
+			torrent, err := t.mapApiItem(entry, result)
+			if err != nil {
+				return nil, err
+			}
+			if torrent != nil {
+				torrents = append(torrents, torrent)
+			}
+		}
+	}
+	return torrents, nil
+}
+
+func (t *TorrentProvider) mapApiItem(entry interface{}, parent interface{}) (*Torrent, error) {
+	mapping := t.config.Api
+	value := func(field string, path string) string {
+		if path != "" {
+			if v, ok := lookupPath(entry, path); ok && v != nil {
+				return stringValue(v)
+			}
+			if v, ok := lookupPath(parent, path); ok && v != nil {
+				return stringValue(v)
+			}
+		}
+		return mapping.Defaults[field]
+	}
+
+	torrent := &Torrent{Provider: t.config.Name}
+	name := value("name", mapping.Fields.Name)
+	if name != "" {
+		info, err := t.parseTorrentTitle(name)
+		if err != nil {
+			return nil, err
+		}
+		if info.Title == "" {
+			return nil, nil
+		}
+		parsedTitle := strings.Trim(strings.ReplaceAll(info.Title, "-", " "), " ")
+		torrent.Title = strings.TrimSpace(titleCleanupRegex.ReplaceAllString(parsedTitle, ""))
+		torrent.OriginalTitle = name
+		torrent.Resolution = info.Resolution
+		torrent.Quality = info.Quality
+		torrent.Codec = info.Codec
+		torrent.Year = info.Year
+		torrent.Group = strings.TrimSpace(titleCleanupRegex.ReplaceAllString(info.Group, ""))
+		torrent.Season = info.Season
+		torrent.Episode = info.Episode
+	}
+
+	overrideString(&torrent.Title, value("title", mapping.Fields.Title))
+	overrideString(&torrent.OriginalTitle, value("originalTitle", mapping.Fields.OriginalTitle))
+	overrideString(&torrent.Resolution, value("resolution", mapping.Fields.Resolution))
+	overrideString(&torrent.Quality, value("quality", mapping.Fields.Quality))
+	overrideString(&torrent.Codec, value("codec", mapping.Fields.Codec))
+	overrideString(&torrent.Group, value("group", mapping.Fields.Group))
+	overrideString(&torrent.Type, value("type", mapping.Fields.Type))
+	overrideInt(&torrent.Year, value("year", mapping.Fields.Year))
+	overrideInt(&torrent.Season, value("season", mapping.Fields.Season))
+	overrideInt(&torrent.Episode, value("episode", mapping.Fields.Episode))
+	torrent.Group = strings.ToLower(torrent.Group)
+	torrent.Seeds = intValue(value("seeds", mapping.Fields.Seeds))
+	torrent.Peers = intValue(value("peers", mapping.Fields.Peers))
+
+	if torrent.Type == "" {
+		torrent.Type = "movie"
+		if torrent.Season != 0 {
+			torrent.Type = "serie"
+		}
+	}
+
+	torrent.Size = value("size", mapping.Fields.Size)
+	if sizeBytes := value("sizeBytes", mapping.Fields.SizeBytes); sizeBytes != "" {
+		torrent.SizeBytes, _ = strconv.ParseInt(sizeBytes, 10, 64)
+	}
+	if torrent.SizeBytes == 0 && torrent.Size != "" {
+		torrent.SizeBytes, _ = SizeToBytes(torrent.Size)
+	}
+	if torrent.Size == "" {
+		torrent.Size = FormatSize(torrent.SizeBytes)
+	}
+
+	torrent.Magnet = value("magnet", mapping.Fields.Magnet)
+	if torrent.Magnet == "" {
+		displayName := name
+		if displayName == "" {
+			displayName = torrent.OriginalTitle
+		}
+		torrent.Magnet = t.formatMagnet(value("infoHash", mapping.Fields.InfoHash), displayName)
+	}
+
+	return torrent, nil
+}
+
+func lookupPath(data interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return data, true
+	}
+	current := data
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = obj[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func intValue(value string) int {
+	number, err := strconv.Atoi(value)
+	if err == nil {
+		return number
+	}
+	float, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return int(float)
+}
+
+func overrideString(target *string, value string) {
+	if value != "" {
+		*target = value
+	}
+}
+
+func overrideInt(target *int, value string) {
+	if value != "" {
+		*target = intValue(value)
+	}
+}
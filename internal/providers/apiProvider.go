@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type apiProvider struct {
+	*TorrentProvider
+}
+
+func newApiProvider(config *ProviderConfig, logger *zerolog.Logger) (Provider, error) {
+	if config.Api == nil {
+		return nil, fmt.Errorf("provider %s of type api has no api mapping", config.Name)
+	}
+	return &apiProvider{TorrentProvider: NewTorrentProvider(config, logger)}, nil
+}
+
+func (t *apiProvider) Search(ctx context.Context, params SearchParams) ([]*Torrent, error) {
+	return t.SearchStream(ctx, params, nil)
+}
+
+func (t *apiProvider) SearchStream(ctx context.Context, params SearchParams, emit func(*Torrent)) ([]*Torrent, error) {
+	return t.withMirrors(ctx, func(mirror string) ([]*Torrent, error) {
+		return t.fetchByApi(ctx, mirror, params, emit)
+	})
+}
+
+func (t *apiProvider) fetchByApi(ctx context.Context, mirror string, params SearchParams, emit func(*Torrent)) ([]*Torrent, error) {
+	var torrents []*Torrent
+	for page := 1; page <= t.maxPages(); page++ {
+		baseUrl := t.pageUrl(mirror, params, page)
+		if baseUrl == "" {
+			break
+		}
+		items, err := t.fetchPage(ctx, baseUrl)
+		if err != nil {
+			// later pages only add results, a failure there keeps what was found
+			if page > 1 {
+				t.logger.Err(err).Msgf("error while fetching page %d: %s, %v", page, baseUrl, err)
+				break
+			}
+			return nil, err
+		}
+		if len(items) == 0 {
+			break
+		}
+		for _, item := range items {
+			item.Mirror = mirror
+		}
+		if emit != nil {
+			for _, item := range items {
+				emit(item)
+			}
+		}
+		torrents = append(torrents, items...)
+	}
+
+	t.logger.Info().Msgf("Provider: %s, got %d results", t.config.Name, len(torrents))
+
+	return torrents, nil
+}
+
+func (t *apiProvider) fetchPage(ctx context.Context, baseUrl string) ([]*Torrent, error) {
+	t.logger.Info().Msgf("Fetch API: %s", baseUrl)
+
+	resp, err := t.rs.R().SetHeader("Content-Type", "application/json").SetContext(ctx).Get(baseUrl)
+	if err != nil {
+		t.logger.Err(err).Msgf("error while fetching: %s, %v", baseUrl, err)
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("error while fetching: %s, status %d", baseUrl, resp.StatusCode())
+	}
+
+	items, err := t.mapApiResponse(resp.Body())
+	if err != nil {
+		t.logger.Err(err).Msgf("error while mapping api response from: %s, %v", baseUrl, err)
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,162 @@
+package providers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/xochilpili/torrent-api-go/internal/config"
+)
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// BreakerConfig overrides the global circuit breaker settings for a provider.
+type BreakerConfig struct {
+	Failures int    `json:"failures,omitempty"`
+	Cooldown string `json:"cooldown,omitempty"`
+}
+
+type breakerState struct {
+	state     string
+	failures  int
+	trips     int
+	openUntil time.Time
+	probing   bool
+}
+
+// circuitBreakers trips a provider after too many consecutive failures, it is
+// skipped while open and gets a single trial search once the cool-down is
+// over, each failed trial doubles the cool-down up to maxCooldown.
+type circuitBreakers struct {
+	mu          sync.Mutex
+	enabled     bool
+	failures    int
+	cooldown    time.Duration
+	maxCooldown time.Duration
+	providers   map[string]*breakerState
+}
+
+func newCircuitBreakers(cfg *config.Config) *circuitBreakers {
+	return &circuitBreakers{
+		enabled:     cfg.BreakerEnabled,
+		failures:    cfg.BreakerFailures,
+		cooldown:    cfg.BreakerCooldown,
+		maxCooldown: cfg.BreakerMaxCooldown,
+		providers:   make(map[string]*breakerState),
+	}
+}
+
+func (b *circuitBreakers) settings(conf *ProviderConfig) (int, time.Duration) {
+	failures, cooldown := b.failures, b.cooldown
+	if conf.Breaker != nil {
+		if conf.Breaker.Failures > 0 {
+			failures = conf.Breaker.Failures
+		}
+		if value, err := time.ParseDuration(conf.Breaker.Cooldown); err == nil && value > 0 {
+			cooldown = value
+		}
+	}
+	if failures < 1 {
+		failures = 1
+	}
+	return failures, cooldown
+}
+
+func (b *circuitBreakers) get(name string) *breakerState {
+	state, ok := b.providers[name]
+	if !ok {
+		state = &breakerState{state: BreakerClosed}
+		b.providers[name] = state
+	}
+	return state
+}
+
+// allow reports whether the provider may be searched, otherwise it returns
+// how long until it is retried.
+func (b *circuitBreakers) allow(conf *ProviderConfig) (bool, time.Duration) {
+	if b == nil || !b.enabled {
+		return true, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := b.get(conf.Name)
+	switch state.state {
+	case BreakerOpen:
+		if wait := time.Until(state.openUntil); wait > 0 {
+			return false, wait
+		}
+		state.state = BreakerHalfOpen
+		state.probing = true
+		return true, 0
+	case BreakerHalfOpen:
+		if state.probing {
+			return false, 0
+		}
+		state.probing = true
+		return true, 0
+	}
+	return true, 0
+}
+
+func (b *circuitBreakers) success(conf *ProviderConfig) {
+	if b == nil || !b.enabled {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	*b.get(conf.Name) = breakerState{state: BreakerClosed}
+}
+
+func (b *circuitBreakers) failure(conf *ProviderConfig) {
+	if b == nil || !b.enabled {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := b.get(conf.Name)
+	state.failures++
+	state.probing = false
+	failures, cooldown := b.settings(conf)
+	if state.state == BreakerClosed && state.failures < failures {
+		return
+	}
+	state.trips++
+	for i := 1; i < state.trips && cooldown < b.maxCooldown; i++ {
+		cooldown *= 2
+	}
+	if b.maxCooldown > 0 && cooldown > b.maxCooldown {
+		cooldown = b.maxCooldown
+	}
+	state.state = BreakerOpen
+	state.openUntil = time.Now().Add(cooldown)
+}
+
+// release gives back a half-open trial whose search was abandoned by the caller.
+func (b *circuitBreakers) release(conf *ProviderConfig) {
+	if b == nil || !b.enabled {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.get(conf.Name).probing = false
+}
+
+func (b *circuitBreakers) state(name string) string {
+	if b == nil || !b.enabled {
+		return BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.get(name).state
+}
+
+func skippedError(wait time.Duration) string {
+	if wait <= 0 {
+		return "circuit breaker half-open, waiting for a trial search"
+	}
+	return fmt.Sprintf("circuit breaker open, retrying in %s", wait.Round(time.Second))
+}
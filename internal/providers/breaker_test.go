@@ -0,0 +1,33 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xochilpili/torrent-api-go/internal/config"
+)
+
+func TestFetchByProviderSkippedByBreaker(t *testing.T) {
+	manager := newTestManager(t)
+	manager.breakers = newCircuitBreakers(&config.Config{BreakerEnabled: true, BreakerFailures: 1, BreakerCooldown: time.Minute})
+	providers, err := manager.GetActiveProviders()
+	if err != nil || len(providers) == 0 {
+		t.Fatalf("no active providers: %v", err)
+	}
+	conf := providers[0]
+	manager.breakers.failure(conf)
+
+	_, err = manager.FetchByProvider(context.Background(), conf.Name, SearchParams{Query: "breaker%20test"})
+	if !errors.Is(err, ErrProviderUnavailable) {
+		t.Fatalf("expected the provider to be unavailable, got %v", err)
+	}
+	var unavailable *ProviderUnavailableError
+	if !errors.As(err, &unavailable) || unavailable.Status.Status != ProviderStatusSkipped {
+		t.Fatalf("expected a skipped status, got %v", err)
+	}
+	if unavailable.RetryAfter <= 0 || unavailable.RetryAfter > time.Minute {
+		t.Fatalf("expected the remaining cool-down, got %s", unavailable.RetryAfter)
+	}
+}
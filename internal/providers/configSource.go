@@ -0,0 +1,189 @@
+package providers
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// the default provider configs and filter profiles are built into the binary
+//
+//go:embed config
+var embeddedConfig embed.FS
+
+var configExtensions = []string{".json", ".yaml", ".yml"}
+
+// configLayer is a set of config files, later layers take precedence.
+type configLayer struct {
+	name string
+	fsys fs.FS
+}
+
+// providerDocument is the merged config of a provider along with the files it came from.
+type providerDocument struct {
+	sources []string
+	data    map[string]interface{}
+}
+
+func (d *providerDocument) source() string {
+	return strings.Join(d.sources, " + ")
+}
+
+// configLayers returns the embedded configs followed by TAG_PROVIDERS_DIR and
+// the admin overlay dir when set.
+func (p *TorrentManager) configLayers() ([]configLayer, error) {
+	embedded, err := fs.Sub(embeddedConfig, "config")
+	if err != nil {
+		return nil, err
+	}
+	layers := []configLayer{{name: "embedded", fsys: embedded}}
+	if p.config.ProvidersDir != "" {
+		info, err := os.Stat(p.config.ProvidersDir)
+		if err != nil {
+			return nil, fmt.Errorf("providers dir: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("providers dir %s is not a directory", p.config.ProvidersDir)
+		}
+		layers = append(layers, configLayer{name: p.config.ProvidersDir, fsys: os.DirFS(p.config.ProvidersDir)})
+	}
+	// the admin api overlay only exists once something was overridden
+	if p.config.OverlayDir != "" {
+		if info, err := os.Stat(p.config.OverlayDir); err == nil && info.IsDir() {
+			layers = append(layers, configLayer{name: p.config.OverlayDir, fsys: os.DirFS(p.config.OverlayDir)})
+		}
+	}
+	return layers, nil
+}
+
+// providerDocuments reads the provider files of every layer keyed by file
+// basename, a file overriding a provider of a previous layer only replaces
+// the fields it sets, nested objects included. Files that cannot be read are
+// reported in the returned ValidationErrors and skipped so every broken file
+// shows up at once.
+func (p *TorrentManager) providerDocuments() (map[string]*providerDocument, ValidationErrors, error) {
+	layers, err := p.configLayers()
+	if err != nil {
+		return nil, nil, err
+	}
+	documents := make(map[string]*providerDocument)
+	var problems ValidationErrors
+	for _, layer := range layers {
+		entries, err := fs.ReadDir(layer.fsys, ".")
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %s configs: %w", layer.name, err)
+		}
+		found := make(map[string]string)
+		for _, entry := range entries {
+			// nested directories hold other configs, like filter profiles
+			if entry.IsDir() || !isConfigFile(entry.Name()) {
+				continue
+			}
+			name := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+			source := path.Join(layer.name, entry.Name())
+			if previous, ok := found[name]; ok {
+				problems = append(problems, &ValidationError{File: source, Message: fmt.Sprintf("provider %s is also defined by %s", name, path.Join(layer.name, previous))})
+				continue
+			}
+			found[name] = entry.Name()
+
+			data, err := readConfigDocument(layer.fsys, entry.Name())
+			if err != nil {
+				problems = append(problems, &ValidationError{File: source, Message: err.Error()})
+				continue
+			}
+			document, ok := documents[name]
+			if !ok {
+				documents[name] = &providerDocument{sources: []string{source}, data: data}
+				continue
+			}
+			document.sources = append(document.sources, source)
+			document.data = mergeDocuments(document.data, data)
+		}
+	}
+	return documents, problems, nil
+}
+
+// readLayeredFile returns the file of the last layer that has it, trying every
+// config extension, with its source.
+func (p *TorrentManager) readLayeredFile(name string) (map[string]interface{}, string, error) {
+	layers, err := p.configLayers()
+	if err != nil {
+		return nil, "", err
+	}
+	for i := len(layers) - 1; i >= 0; i-- {
+		for _, ext := range configExtensions {
+			data, err := readConfigDocument(layers[i].fsys, name+ext)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			source := path.Join(layers[i].name, name+ext)
+			if err != nil {
+				return nil, source, err
+			}
+			return data, source, nil
+		}
+	}
+	return nil, "", fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+}
+
+func isConfigFile(name string) bool {
+	return containsString(configExtensions, path.Ext(name))
+}
+
+// readConfigDocument decodes a json or yaml file into a generic json document.
+func readConfigDocument(fsys fs.FS, name string) (map[string]interface{}, error) {
+	raw, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if path.Ext(name) != ".json" {
+		var value interface{}
+		if err := yaml.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		// going through json keeps the json tags as the only schema
+		if raw, err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func mergeDocuments(base map[string]interface{}, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		baseObject, baseOk := merged[key].(map[string]interface{})
+		overrideObject, overrideOk := value.(map[string]interface{})
+		if baseOk && overrideOk {
+			merged[key] = mergeDocuments(baseObject, overrideObject)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+func sortedDocumentNames(documents map[string]*providerDocument) []string {
+	var names []string
+	for name := range documents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
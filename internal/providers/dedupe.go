@@ -0,0 +1,53 @@
+package providers
+
+// dedupe merges torrents sharing an info hash into the first one seen, keeping
+// the highest seeds and peers, every tracker and every provider reporting it.
+func (p *TorrentManager) dedupe(items []*Torrent) []*Torrent {
+	var deduped []*Torrent
+	seen := make(map[string]*Torrent)
+	for _, item := range items {
+		if item.InfoHash == "" {
+			item.InfoHash = InfoHashFromMagnet(item.Magnet)
+		}
+		if len(item.Sources) == 0 {
+			item.Sources = []string{item.Provider}
+		}
+		if item.InfoHash == "" {
+			deduped = append(deduped, item)
+			continue
+		}
+
+		merged, ok := seen[item.InfoHash]
+		if !ok {
+			seen[item.InfoHash] = item
+			deduped = append(deduped, item)
+			continue
+		}
+
+		if item.Seeds > merged.Seeds {
+			merged.Seeds = item.Seeds
+		}
+		if item.Peers > merged.Peers {
+			merged.Peers = item.Peers
+		}
+		merged.Magnet = addMagnetTrackers(merged.Magnet, magnetTrackers(item.Magnet))
+		for _, source := range item.Sources {
+			if !containsString(merged.Sources, source) {
+				merged.Sources = append(merged.Sources, source)
+			}
+		}
+	}
+	if len(deduped) != len(items) {
+		p.logger.Info().Msgf("merged %d duplicated torrents", len(items)-len(deduped))
+	}
+	return deduped
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
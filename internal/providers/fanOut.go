@@ -0,0 +1,240 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/xochilpili/torrent-api-go/internal/metrics"
+)
+
+const (
+	ProviderStatusOk      = "ok"
+	ProviderStatusTimeout = "timeout"
+	ProviderStatusError   = "error"
+	ProviderStatusSkipped = "skipped"
+)
+
+const defaultProviderTimeout = 30 * time.Second
+
+type ProviderStatus struct {
+	Provider string `json:"provider"`
+	Status   string `json:"status"`
+	Results  int    `json:"results"`
+	Latency  int64  `json:"latency_ms"`
+	Cached   bool   `json:"cached"`
+	CacheAge int64  `json:"cache_age_seconds,omitempty"`
+	Error    string `json:"error,omitempty"`
+
+	// retryAfter is set when an open breaker skipped the provider
+	retryAfter time.Duration
+}
+
+type SearchResult struct {
+	Torrents  []*Torrent        `json:"data"`
+	Providers []*ProviderStatus `json:"providers"`
+	Total     int               `json:"total"`
+	Page      int               `json:"page"`
+	Limit     int               `json:"limit"`
+}
+
+// Partial reports whether any provider failed to answer, in which case
+// Torrents may be missing releases.
+func (r *SearchResult) Partial() bool {
+	for _, status := range r.Providers {
+		if status.Status != ProviderStatusOk {
+			return true
+		}
+	}
+	return false
+}
+
+type providerResult struct {
+	status   *ProviderStatus
+	torrents []*Torrent
+}
+
+// fanOut searches every provider concurrently, stream is optional and
+// receives torrents and provider statuses as they come.
+func (p *TorrentManager) fanOut(ctx context.Context, cfg []*ProviderConfig, params SearchParams, stream *searchStream) *SearchResult {
+	results := make(chan *providerResult, len(cfg))
+	for _, conf := range cfg {
+		go func(conf *ProviderConfig) {
+			result := p.searchProvider(ctx, conf, params, stream)
+			if stream != nil {
+				stream.status(result.status)
+			}
+			results <- result
+		}(conf)
+	}
+
+	result := &SearchResult{}
+	for range cfg {
+		providerResult := <-results
+		result.Providers = append(result.Providers, providerResult.status)
+		result.Torrents = append(result.Torrents, providerResult.torrents...)
+	}
+	sort.Slice(result.Providers, func(i, j int) bool {
+		return result.Providers[i].Provider < result.Providers[j].Provider
+	})
+	return result
+}
+
+func (p *TorrentManager) searchProvider(ctx context.Context, conf *ProviderConfig, params SearchParams, stream *searchStream) *providerResult {
+	start := time.Now()
+	result := &providerResult{status: &ProviderStatus{Provider: conf.Name}}
+	defer func() {
+		elapsed := time.Since(start)
+		result.status.Latency = elapsed.Milliseconds()
+		metrics.ProviderSearches.WithLabelValues(conf.Name, strconv.FormatBool(result.status.Cached)).Inc()
+		metrics.ProviderLatency.WithLabelValues(conf.Name).Observe(elapsed.Seconds())
+		metrics.Results.WithLabelValues(conf.Name, "received").Add(float64(len(result.torrents)))
+		if result.status.Status != ProviderStatusOk {
+			metrics.ProviderErrors.WithLabelValues(conf.Name, result.status.Status).Inc()
+		}
+	}()
+
+	var emit func(*Torrent)
+	if stream != nil {
+		emit = stream.torrent
+	}
+
+	key := searchCacheKey(conf.Name, params.Query)
+	if p.cache != nil {
+		if cached, ok := p.cache.get(key); ok {
+			result.status.Status = ProviderStatusOk
+			result.status.Results = len(cached.torrents)
+			result.status.Cached = true
+			result.status.CacheAge = int64(cached.age.Seconds())
+			result.torrents = cached.torrents
+			if emit != nil {
+				for _, torrent := range cached.torrents {
+					emit(torrent)
+				}
+			}
+			if cached.stale && p.cache.startRefresh(key) {
+				go p.refreshCache(conf, params, key)
+			}
+			return result
+		}
+	}
+
+	if allowed, wait := p.breakers.allow(conf); !allowed {
+		result.status.Status = ProviderStatusSkipped
+		result.status.Error = skippedError(wait)
+		result.status.retryAfter = wait
+		return result
+	}
+
+	torrents, err := p.runSearch(ctx, conf, params, emit)
+	switch {
+	case err == nil:
+		p.breakers.success(conf)
+	case ctx.Err() != nil:
+		// the caller went away, that says nothing about the provider
+		p.breakers.release(conf)
+	default:
+		p.breakers.failure(conf)
+	}
+	if err == nil {
+		result.status.Status = ProviderStatusOk
+		result.status.Results = len(torrents)
+		result.torrents = torrents
+		p.storeCache(key, torrents)
+		return result
+	}
+
+	result.status.Error = err.Error()
+	if errors.Is(err, context.DeadlineExceeded) {
+		p.logger.Warn().Msgf("provider %s timed out after %s", conf.Name, conf.SearchTimeout())
+		result.status.Status = ProviderStatusTimeout
+		result.status.Error = fmt.Sprintf("timed out after %s", conf.SearchTimeout())
+		return result
+	}
+	p.logger.Err(err).Msgf("error while searching provider %s: %v", conf.Name, err)
+	result.status.Status = ProviderStatusError
+	return result
+}
+
+// runSearch queries a provider bounded by its own timeout, the returned error
+// wraps context.DeadlineExceeded when the provider was too slow. When emit is
+// set it gets every torrent, as soon as found for streaming providers.
+func (p *TorrentManager) runSearch(ctx context.Context, conf *ProviderConfig, params SearchParams, emit func(*Torrent)) ([]*Torrent, error) {
+	provider, err := NewProvider(conf, p.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, conf.SearchTimeout())
+	defer cancel()
+
+	type searchResponse struct {
+		torrents []*Torrent
+		err      error
+	}
+	done := make(chan searchResponse, 1)
+	go func() {
+		streaming, ok := provider.(StreamingProvider)
+		if !ok || emit == nil {
+			torrents, err := provider.Search(ctx, params)
+			if err == nil && emit != nil {
+				for _, torrent := range torrents {
+					emit(torrent)
+				}
+			}
+			done <- searchResponse{torrents: torrents, err: err}
+			return
+		}
+		torrents, err := streaming.SearchStream(ctx, params, func(torrent *Torrent) {
+			// a provider may still find items after giving up on it
+			if ctx.Err() == nil {
+				emit(torrent)
+			}
+		})
+		done <- searchResponse{torrents: torrents, err: err}
+	}()
+
+	select {
+	case response := <-done:
+		for _, torrent := range response.torrents {
+			normalizeTorrent(torrent)
+		}
+		return response.torrents, response.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *TorrentManager) refreshCache(conf *ProviderConfig, params SearchParams, key string) {
+	defer p.cache.endRefresh(key)
+	p.logger.Info().Msgf("refreshing cached results for provider %s", conf.Name)
+	torrents, err := p.runSearch(context.Background(), conf, params, nil)
+	if err != nil {
+		p.logger.Err(err).Msgf("error while refreshing cache for provider %s: %v", conf.Name, err)
+		return
+	}
+	p.storeCache(key, torrents)
+}
+
+func (p *TorrentManager) storeCache(key string, torrents []*Torrent) {
+	if p.cache == nil {
+		return
+	}
+	if err := p.cache.set(key, torrents); err != nil {
+		p.logger.Err(err).Msgf("error while caching results for %s: %v", key, err)
+	}
+}
+
+// normalizeTorrent fills the fields every provider is expected to have but
+// that can be derived from others.
+func normalizeTorrent(torrent *Torrent) {
+	if torrent.InfoHash == "" {
+		torrent.InfoHash = InfoHashFromMagnet(torrent.Magnet)
+	}
+	if torrent.SizeBytes == 0 && torrent.Size != "" {
+		torrent.SizeBytes, _ = SizeToBytes(torrent.Size)
+	}
+}
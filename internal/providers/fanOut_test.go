@@ -0,0 +1,70 @@
+package providers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/torrent-api-go/internal/config"
+)
+
+func newTestManager(t *testing.T) *TorrentManager {
+	t.Helper()
+	logger := zerolog.Nop()
+	manager, err := NewTorrentManager(&config.Config{
+		CacheEnabled:  true,
+		CacheBackend:  "memory",
+		CacheSize:     10,
+		CacheTTL:      time.Minute,
+		CacheStaleTTL: time.Hour,
+		HealthHistory: 1,
+	}, &logger)
+	if err != nil {
+		t.Fatalf("error while creating manager: %v", err)
+	}
+	return manager
+}
+
+func TestStreamEmitsCachedTorrents(t *testing.T) {
+	manager := newTestManager(t)
+	providers, err := manager.GetActiveProviders()
+	if err != nil || len(providers) == 0 {
+		t.Fatalf("no active providers: %v", err)
+	}
+	conf := providers[0]
+	params := SearchParams{Query: "some%20show"}
+	cached := []*Torrent{
+		{Provider: conf.Name, OriginalTitle: "Some.Show.S01E01.1080p", Magnet: "magnet:?xt=urn:btih:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+		{Provider: conf.Name, OriginalTitle: "Some.Show.S01E02.1080p", Magnet: "magnet:?xt=urn:btih:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"},
+	}
+	manager.storeCache(searchCacheKey(conf.Name, params.Query), cached)
+
+	profile, err := manager.searchProfile(params)
+	if err != nil {
+		t.Fatalf("error while resolving profile: %v", err)
+	}
+	var events []*StreamEvent
+	stream := &searchStream{
+		emit:    func(event *StreamEvent) { events = append(events, event) },
+		keep:    func(*Torrent) bool { return true },
+		scoring: profile.scoring,
+	}
+	result := manager.fanOut(context.Background(), []*ProviderConfig{conf}, params, stream)
+
+	if status := result.Providers[0]; !status.Cached || status.Status != ProviderStatusOk {
+		t.Fatalf("expected a cached ok status, got %+v", status)
+	}
+	var torrents []string
+	for _, event := range events {
+		if event.Event == StreamEventTorrent {
+			torrents = append(torrents, event.Torrent.InfoHash)
+		}
+	}
+	if len(torrents) != len(cached) {
+		t.Fatalf("expected %d torrent events for the cache hit, got %d", len(cached), len(torrents))
+	}
+	if last := events[len(events)-1]; last.Event != StreamEventDone {
+		t.Fatalf("expected the done event after the torrents, got %s", last.Event)
+	}
+}
@@ -0,0 +1,148 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// filterProfilesFile is looked up in every config layer without extension.
+const filterProfilesFile = "filters/profiles"
+
+type SizeRange struct {
+	Min string `json:"min,omitempty"`
+	Max string `json:"max,omitempty"`
+}
+
+// FilterProfile is a named set of rules applied to every result, Sizes is
+// keyed by torrent type and then by resolution, "default" matches any resolution.
+type FilterProfile struct {
+	Name              string                          `json:"name"`
+	Sizes             map[string]map[string]SizeRange `json:"sizes,omitempty"`
+	ExcludedQualities []string                        `json:"excludedQualities,omitempty"`
+	RequiredCodecs    []string                        `json:"requiredCodecs,omitempty"`
+	ForbiddenCodecs   []string                        `json:"forbiddenCodecs,omitempty"`
+	MinSeeds          int                             `json:"minSeeds,omitempty"`
+	AllowedGroups     []string                        `json:"allowedGroups,omitempty"`
+	Scoring           *ScoringWeights                 `json:"scoring,omitempty"`
+
+	sizes   map[string]map[string]byteRange
+	scoring *ScoringWeights
+}
+
+type FilterProfiles struct {
+	Default  string           `json:"default"`
+	Profiles []*FilterProfile `json:"profiles"`
+}
+
+type byteRange struct {
+	min int64
+	max int64
+}
+
+// loadFilterProfiles reads the profiles of the last config layer defining
+// them, profiles files replace each other instead of being merged.
+func (p *TorrentManager) loadFilterProfiles() (*FilterProfiles, error) {
+	data, file, err := p.readLayeredFile(filterProfilesFile)
+	if file == "" {
+		file = filterProfilesFile
+	}
+	// problems point at the layer the profiles were read from
+	invalid := func(err error) error {
+		return ValidationErrors{{File: file, Message: err.Error()}}
+	}
+	if err != nil {
+		return nil, invalid(err)
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, invalid(err)
+	}
+
+	var profiles FilterProfiles
+	if err := json.Unmarshal(raw, &profiles); err != nil {
+		return nil, invalid(err)
+	}
+	for _, profile := range profiles.Profiles {
+		if err := profile.parse(); err != nil {
+			return nil, invalid(err)
+		}
+	}
+	if profiles.Get(profiles.Default) == nil {
+		return nil, invalid(fmt.Errorf("default profile %q not found", profiles.Default))
+	}
+	return &profiles, nil
+}
+
+// Get returns the named profile, an empty name resolves to the default profile.
+func (f *FilterProfiles) Get(name string) *FilterProfile {
+	if name == "" {
+		name = f.Default
+	}
+	for _, profile := range f.Profiles {
+		if strings.EqualFold(profile.Name, name) {
+			return profile
+		}
+	}
+	return nil
+}
+
+func (f *FilterProfile) parse() error {
+	f.scoring = f.Scoring.merge(defaultScoringWeights())
+	if err := f.scoring.parse(); err != nil {
+		return fmt.Errorf("profile %s: %w", f.Name, err)
+	}
+
+	f.sizes = make(map[string]map[string]byteRange)
+	for itemType, resolutions := range f.Sizes {
+		f.sizes[itemType] = make(map[string]byteRange)
+		for resolution, sizeRange := range resolutions {
+			var parsed byteRange
+			var err error
+			if sizeRange.Min != "" {
+				if parsed.min, err = SizeToBytes(sizeRange.Min); err != nil {
+					return fmt.Errorf("profile %s: invalid min size for %s %s: %w", f.Name, itemType, resolution, err)
+				}
+			}
+			if sizeRange.Max != "" {
+				if parsed.max, err = SizeToBytes(sizeRange.Max); err != nil {
+					return fmt.Errorf("profile %s: invalid max size for %s %s: %w", f.Name, itemType, resolution, err)
+				}
+			}
+			f.sizes[itemType][strings.ToLower(resolution)] = parsed
+		}
+	}
+	return nil
+}
+
+func (f *FilterProfile) sizeRange(itemType string, resolution string) (byteRange, bool) {
+	resolutions, ok := f.sizes[itemType]
+	if !ok {
+		return byteRange{}, false
+	}
+	if sizeRange, ok := resolutions[strings.ToLower(resolution)]; ok {
+		return sizeRange, true
+	}
+	sizeRange, ok := resolutions["default"]
+	return sizeRange, ok
+}
+
+func normalizeCodec(codec string) string {
+	return strings.NewReplacer(".", "", "-", "", " ", "").Replace(strings.ToLower(codec))
+}
+
+func normalizeGroup(group string) string {
+	return strings.ToLower(strings.TrimSpace(group))
+}
+
+// matchesAny compares whole values once normalized, a group "ntb" must not
+// let "ntbx" through.
+func matchesAny(value string, candidates []string, normalize func(string) string) bool {
+	value = normalize(value)
+	for _, candidate := range candidates {
+		if value != "" && value == normalize(candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package providers
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	HealthStatusUnknown   = "unknown"
+	HealthStatusHealthy   = "healthy"
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+const defaultHealthInterval = 5 * time.Minute
+
+type HealthCheck struct {
+	Status    string    `json:"status"`
+	Query     string    `json:"query"`
+	Results   int       `json:"results"`
+	Magnets   int       `json:"magnets"`
+	Latency   int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type ProviderHealth struct {
+	Provider  string         `json:"provider"`
+	Status    string         `json:"status"`
+	Breaker   string         `json:"breaker"`
+	LastCheck *HealthCheck   `json:"last_check,omitempty"`
+	History   []*HealthCheck `json:"history"`
+}
+
+// healthTracker keeps the latest canary checks of every provider, newest last.
+type healthTracker struct {
+	mu      sync.RWMutex
+	size    int
+	history map[string][]*HealthCheck
+}
+
+func newHealthTracker(size int) *healthTracker {
+	if size < 1 {
+		size = 1
+	}
+	return &healthTracker{size: size, history: make(map[string][]*HealthCheck)}
+}
+
+func (h *healthTracker) record(provider string, check *HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	history := append(h.history[provider], check)
+	if len(history) > h.size {
+		history = history[len(history)-h.size:]
+	}
+	h.history[provider] = history
+}
+
+func (h *healthTracker) get(provider string) *ProviderHealth {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	history := h.history[provider]
+	health := &ProviderHealth{
+		Provider: provider,
+		Status:   HealthStatusUnknown,
+		History:  append([]*HealthCheck{}, history...),
+	}
+	if len(history) > 0 {
+		health.LastCheck = history[len(history)-1]
+		health.Status = health.LastCheck.Status
+	}
+	return health
+}
+
+// RunHealthChecks probes every enabled provider right away and then on each
+// interval, it returns once ctx is done.
+func (p *TorrentManager) RunHealthChecks(ctx context.Context) {
+	interval := p.config.HealthInterval
+	if interval <= 0 {
+		interval = defaultHealthInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.CheckHealth(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// CheckHealth runs the canary search against every enabled provider.
+func (p *TorrentManager) CheckHealth(ctx context.Context) {
+	cfg, err := p.GetActiveProviders()
+	if err != nil {
+		p.logger.Err(err).Msgf("error while loading providers for health checks: %v", err)
+		return
+	}
+	var wg sync.WaitGroup
+	for _, conf := range cfg {
+		wg.Add(1)
+		go func(conf *ProviderConfig) {
+			defer wg.Done()
+			check := p.probeProvider(ctx, conf)
+			if check.Status != HealthStatusHealthy {
+				p.logger.Warn().Msgf("provider %s is unhealthy: %s", conf.Name, check.Error)
+			}
+			p.health.record(conf.Name, check)
+		}(conf)
+	}
+	wg.Wait()
+}
+
+// probeProvider searches the canary query skipping cache and filters, a
+// provider is healthy when its selectors still match and magnets resolve.
+func (p *TorrentManager) probeProvider(ctx context.Context, conf *ProviderConfig) *HealthCheck {
+	query := conf.Canary
+	if query == "" {
+		query = p.config.HealthQuery
+	}
+	check := &HealthCheck{Status: HealthStatusUnhealthy, Query: query, CheckedAt: time.Now()}
+	defer func() {
+		check.Latency = time.Since(check.CheckedAt).Milliseconds()
+	}()
+
+	torrents, err := p.runSearch(ctx, conf, SearchParams{Query: url.PathEscape(query)}, nil)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.Results = len(torrents)
+	for _, torrent := range torrents {
+		if torrent.InfoHash != "" {
+			check.Magnets++
+		}
+	}
+	switch {
+	case check.Results == 0:
+		check.Error = "canary search returned no items, selectors may no longer match"
+	case check.Magnets == 0:
+		check.Error = "no magnet could be resolved from the canary results"
+	default:
+		check.Status = HealthStatusHealthy
+	}
+	return check
+}
+
+// ProvidersHealth reports the health of every enabled provider, sorted by name.
+func (p *TorrentManager) ProvidersHealth() ([]*ProviderHealth, error) {
+	cfg, err := p.GetActiveProviders()
+	if err != nil {
+		return nil, err
+	}
+	var health []*ProviderHealth
+	for _, conf := range cfg {
+		health = append(health, p.providerHealth(conf.Name))
+	}
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].Provider < health[j].Provider
+	})
+	return health, nil
+}
+
+func (p *TorrentManager) providerHealth(name string) *ProviderHealth {
+	health := p.health.get(name)
+	health.Breaker = p.breakers.state(name)
+	return health
+}
+
+// Ready reports whether at least HealthMinProviders providers passed their
+// last health check, it is always ready when health checks are disabled.
+func (p *TorrentManager) Ready() (healthy int, ready bool, err error) {
+	health, err := p.ProvidersHealth()
+	if err != nil {
+		return 0, false, err
+	}
+	for _, provider := range health {
+		if provider.Status == HealthStatusHealthy {
+			healthy++
+		}
+	}
+	return healthy, !p.config.HealthEnabled || healthy >= p.config.HealthMinProviders, nil
+}
@@ -0,0 +1,243 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/rs/zerolog"
+)
+
+type htmlProvider struct {
+	*TorrentProvider
+}
+
+func newHtmlProvider(config *ProviderConfig, logger *zerolog.Logger) (Provider, error) {
+	return &htmlProvider{TorrentProvider: NewTorrentProvider(config, logger)}, nil
+}
+
+func (t *htmlProvider) Search(ctx context.Context, params SearchParams) ([]*Torrent, error) {
+	return t.SearchStream(ctx, params, nil)
+}
+
+func (t *htmlProvider) SearchStream(ctx context.Context, params SearchParams, emit func(*Torrent)) ([]*Torrent, error) {
+	return t.withMirrors(ctx, func(mirror string) ([]*Torrent, error) {
+		return t.fetchByScrappe(ctx, mirror, params, emit)
+	})
+}
+
+func (t *htmlProvider) fetchByScrappe(ctx context.Context, mirror string, params SearchParams, emit func(*Torrent)) ([]*Torrent, error) {
+	c := t.newCollector(ctx)
+	c.Limit(&colly.LimitRule{Parallelism: 2, RandomDelay: 5 * time.Second})
+
+	itemSet := make(map[string]bool)
+	itemChan := make(chan *Torrent)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var searchErr error
+	pageItems := make(map[int]int)
+	nextPages := make(map[int]string)
+
+	c.OnHTML(t.config.ItemSelector, func(h *colly.HTMLElement) {
+		mu.Lock()
+		pageItems[requestPage(h.Request)]++
+		mu.Unlock()
+
+		detailUrl := h.ChildAttr(t.config.ItemsSelector.DetailUrl, "href")
+		title := h.ChildText(t.config.ItemsSelector.Title)
+		strSeeds := h.ChildText(t.config.ItemsSelector.Seeds)
+		strPeers := h.ChildText(t.config.ItemsSelector.Peers)
+		size := h.ChildText(t.config.ItemsSelector.Size)
+
+		parsedTitle := strings.ReplaceAll(title, " ", "-")
+
+		info, err := t.parseTorrentTitle(parsedTitle)
+		if err != nil {
+			t.logger.Err(err).Msg("error parsing title")
+			return
+		}
+
+		itemType := "movie"
+		if info.Episode != 0 {
+			itemType = "serie"
+		}
+
+		seeds, err := strconv.Atoi(strSeeds)
+		if err != nil {
+			seeds = 0
+		}
+
+		peers, err := strconv.Atoi(strPeers)
+		if err != nil {
+			peers = 0
+		}
+
+		sizeBytes, err := SizeToBytes(size)
+		if err != nil {
+			t.logger.Info().Msgf("error while casting size: %s, item: %s", err.Error(), title)
+		}
+
+		parsedTitle = strings.Trim(strings.ReplaceAll(info.Title, "-", " "), " ")
+		parsedTitle = strings.TrimSpace(titleCleanupRegex.ReplaceAllString(parsedTitle, ""))
+		group := strings.TrimSpace(titleCleanupRegex.ReplaceAllString(info.Group, ""))
+
+		torrent := Torrent{
+			Provider:      t.config.Name,
+			Mirror:        mirror,
+			Type:          itemType,
+			Title:         parsedTitle,
+			OriginalTitle: title,
+			Resolution:    info.Resolution,
+			Codec:         info.Codec,
+			Quality:       info.Quality,
+			Size:          size,
+			SizeBytes:     sizeBytes,
+			Seeds:         seeds,
+			Peers:         peers,
+			Group:         strings.ToLower(group),
+			Season:        info.Season,
+			Episode:       info.Episode,
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if strings.Contains(detailUrl, t.config.ItemsSelector.MagnetPreffixLink) {
+			baseUrl := fmt.Sprintf("%s%s", mirror, detailUrl)
+			wg.Add(1)
+			go func(link string, item *Torrent, itemChan chan<- *Torrent, wg *sync.WaitGroup) {
+				defer wg.Done()
+				c := colly.NewCollector()
+				t.useTransport(ctx, c)
+				t.instrument(c)
+				c.OnHTML(t.config.ItemsSelector.MagnetSelector, func(h *colly.HTMLElement) {
+					magnetStr := h.Attr("href")
+					if magnetStr == "" {
+						return
+					}
+					mu.Lock()
+					seen := itemSet[item.OriginalTitle]
+					itemSet[item.OriginalTitle] = true
+					mu.Unlock()
+					if !seen {
+						item.Magnet = magnetStr
+						if emit != nil {
+							emit(item)
+						}
+						itemChan <- item
+					}
+				})
+				c.Visit(link)
+			}(baseUrl, &torrent, itemChan, &wg)
+		}
+	})
+
+	if t.config.Pagination != nil && t.config.Pagination.NextPageSelector != "" {
+		c.OnHTML(t.config.Pagination.NextPageSelector, func(h *colly.HTMLElement) {
+			if href := h.Attr("href"); href != "" {
+				mu.Lock()
+				nextPages[requestPage(h.Request)] = h.Request.AbsoluteURL(href)
+				mu.Unlock()
+			}
+		})
+	}
+
+	// pages are visited one after the other, stopping at the first page without items
+	c.OnScraped(func(r *colly.Response) {
+		page := requestPage(r.Request)
+		if page >= t.maxPages() {
+			return
+		}
+		mu.Lock()
+		found := pageItems[page]
+		next := nextPages[page]
+		mu.Unlock()
+		if found == 0 {
+			return
+		}
+		if next == "" {
+			next = t.pageUrl(mirror, params, page+1)
+		}
+		if next == "" {
+			return
+		}
+		if err := t.visitPage(c, next, page+1); err != nil {
+			t.logger.Err(err).Msgf("error while visiting page %d: %s", page+1, next)
+		}
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		// later pages only add results, a failure there keeps what was found
+		if requestPage(r.Request) != 1 {
+			t.logger.Err(err).Msgf("error while scrapping %s: %v", r.Request.URL, err)
+			return
+		}
+		mu.Lock()
+		searchErr = fmt.Errorf("error while scrapping %s: %w", r.Request.URL, err)
+		mu.Unlock()
+	})
+
+	if t.config.Debug {
+		c.OnResponse(func(r *colly.Response) {
+			fmt.Printf("%s", string(r.Body))
+		})
+	}
+
+	baseUrl := t.searchUrl(mirror, params)
+	t.logger.Info().Msgf("Scrapping: %s", baseUrl)
+
+	if err := t.visitPage(c, baseUrl, 1); err != nil {
+		return nil, err
+	}
+	c.Wait()
+
+	go func() {
+		wg.Wait()
+		close(itemChan)
+	}()
+
+	var torrents []*Torrent
+	for items := range itemChan {
+		torrents = append(torrents, items)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if searchErr != nil {
+		return nil, searchErr
+	}
+	if pageItems[1] == 0 {
+		return nil, errNoMatches
+	}
+	t.logger.Info().Msgf("Provider: %s, got %d results", t.config.Name, len(torrents))
+	return torrents, nil
+}
+
+func (t *htmlProvider) visitPage(c *colly.Collector, link string, page int) error {
+	pageCtx := colly.NewContext()
+	pageCtx.Put("page", strconv.Itoa(page))
+	return c.Request("GET", link, nil, pageCtx, nil)
+}
+
+func requestPage(r *colly.Request) int {
+	page, err := strconv.Atoi(r.Ctx.Get("page"))
+	if err != nil {
+		return 1
+	}
+	return page
+}
+
+// abortOnDone stops colly from issuing new requests once the search context is gone.
+func abortOnDone(ctx context.Context) colly.RequestCallback {
+	return func(r *colly.Request) {
+		if ctx.Err() != nil {
+			r.Abort()
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package providers
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var btihRegex = regexp.MustCompile(`(?i)xt=urn:btih:([a-z0-9]+)`)
+
+// InfoHashFromMagnet returns the lowercase hex BTIH of a magnet link,
+// base32 encoded hashes are converted to hex.
+func InfoHashFromMagnet(magnet string) string {
+	matches := btihRegex.FindStringSubmatch(magnet)
+	if len(matches) < 2 {
+		return ""
+	}
+	hash := matches[1]
+	switch len(hash) {
+	case 40:
+		return strings.ToLower(hash)
+	case 32:
+		decoded, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+		if err != nil {
+			return ""
+		}
+		return hex.EncodeToString(decoded)
+	}
+	return strings.ToLower(hash)
+}
+
+func magnetTrackers(magnet string) []string {
+	u, err := url.Parse(magnet)
+	if err != nil {
+		return nil
+	}
+	return u.Query()["tr"]
+}
+
+func addMagnetTrackers(magnet string, trackers []string) string {
+	known := make(map[string]bool)
+	for _, tracker := range magnetTrackers(magnet) {
+		known[tracker] = true
+	}
+	for _, tracker := range trackers {
+		if known[tracker] {
+			continue
+		}
+		known[tracker] = true
+		magnet += "&tr=" + url.QueryEscape(tracker)
+	}
+	return magnet
+}
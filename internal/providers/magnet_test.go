@@ -0,0 +1,20 @@
+package providers
+
+import "testing"
+
+func TestInfoHashFromMagnet(t *testing.T) {
+	tests := []struct {
+		magnet   string
+		expected string
+	}{
+		{magnet: "magnet:?xt=urn:btih:DD8255ECDC7CA55FB0BBF81323D87062DB1F6D1C&dn=matrix", expected: "dd8255ecdc7ca55fb0bbf81323d87062db1f6d1c"},
+		{magnet: "magnet:?xt=urn:btih:3WBFL3G4PSSV7MF37AJSHWDQMLNR63I4&tr=udp://tracker", expected: "dd8255ecdc7ca55fb0bbf81323d87062db1f6d1c"},
+		{magnet: "magnet:?dn=matrix&xt=urn:btih:3wbfl3g4pssv7mf37ajshwdqmlnr63i4", expected: "dd8255ecdc7ca55fb0bbf81323d87062db1f6d1c"},
+		{magnet: "magnet:?dn=matrix", expected: ""},
+	}
+	for _, test := range tests {
+		if hash := InfoHashFromMagnet(test.magnet); hash != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.magnet, test.expected, hash)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	MirrorPolicyFailover = "failover"
+	MirrorPolicyFastest  = "fastest"
+)
+
+// errNoMatches is returned by a search whose first page matched no item,
+// which is what a parked domain or a challenge page usually looks like.
+var errNoMatches = errors.New("no items matched the item selector")
+
+type mirrorStat struct {
+	latency  time.Duration
+	failures int
+}
+
+// mirrorStats is shared by every provider instance since providers are built
+// per search, mirrors are keyed by url.
+var mirrorStats = &mirrorTracker{stats: make(map[string]*mirrorStat)}
+
+type mirrorTracker struct {
+	mu    sync.RWMutex
+	stats map[string]*mirrorStat
+}
+
+func (m *mirrorTracker) success(mirror string, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stat, ok := m.stats[mirror]
+	if !ok {
+		m.stats[mirror] = &mirrorStat{latency: latency}
+		return
+	}
+	stat.failures = 0
+	if stat.latency == 0 {
+		stat.latency = latency
+		return
+	}
+	// moving average so a single slow answer does not reorder mirrors
+	stat.latency = (stat.latency*7 + latency*3) / 10
+}
+
+func (m *mirrorTracker) failure(mirror string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stat, ok := m.stats[mirror]
+	if !ok {
+		stat = &mirrorStat{}
+		m.stats[mirror] = stat
+	}
+	stat.failures++
+}
+
+func (m *mirrorTracker) get(mirror string) mirrorStat {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if stat, ok := m.stats[mirror]; ok {
+		return *stat
+	}
+	return mirrorStat{}
+}
+
+// mirrors returns the base url followed by the configured mirrors, ordered by
+// the mirror policy. Fastest puts failing mirrors last and unmeasured ones
+// after the measured ones.
+func (t *TorrentProvider) mirrors() []string {
+	var mirrors []string
+	for _, mirror := range append([]string{t.config.BaseUrl}, t.config.Mirrors...) {
+		if mirror != "" && !containsString(mirrors, mirror) {
+			mirrors = append(mirrors, mirror)
+		}
+	}
+	if t.config.MirrorPolicy != MirrorPolicyFastest {
+		return mirrors
+	}
+
+	stats := make(map[string]mirrorStat)
+	for _, mirror := range mirrors {
+		stats[mirror] = mirrorStats.get(mirror)
+	}
+	sort.SliceStable(mirrors, func(i, j int) bool {
+		a, b := stats[mirrors[i]], stats[mirrors[j]]
+		if a.failures != b.failures {
+			return a.failures < b.failures
+		}
+		if a.latency == 0 || b.latency == 0 {
+			return a.latency != 0 && b.latency == 0
+		}
+		return a.latency < b.latency
+	})
+	return mirrors
+}
+
+// withMirrors runs search on each mirror until one succeeds. Mirrors that
+// answered without matches count as failed, the search only ends empty when
+// every mirror answered without matches.
+func (t *TorrentProvider) withMirrors(ctx context.Context, search func(mirror string) ([]*Torrent, error)) ([]*Torrent, error) {
+	var lastErr error
+	mirrors := t.mirrors()
+	noMatches := 0
+	for _, mirror := range mirrors {
+		start := time.Now()
+		torrents, err := search(mirror)
+		if err == nil {
+			mirrorStats.success(mirror, time.Since(start))
+			return torrents, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		mirrorStats.failure(mirror)
+		t.logger.Warn().Msgf("provider %s mirror %s failed: %v", t.config.Name, mirror, err)
+		if errors.Is(err, errNoMatches) {
+			noMatches++
+			continue
+		}
+		lastErr = err
+	}
+	if noMatches == len(mirrors) {
+		return nil, nil
+	}
+	return nil, lastErr
+}
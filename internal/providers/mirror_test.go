@@ -0,0 +1,63 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func newMirrorProvider(name string, mirrors ...string) *TorrentProvider {
+	logger := zerolog.Nop()
+	return NewTorrentProvider(&ProviderConfig{
+		Name:         name,
+		BaseUrl:      mirrors[0],
+		Mirrors:      mirrors[1:],
+		MirrorPolicy: MirrorPolicyFastest,
+	}, &logger)
+}
+
+func TestMirrorsFailOverWithoutMatches(t *testing.T) {
+	provider := newMirrorProvider("parked", "https://parked.mirror.test", "https://live.mirror.test")
+	var visited []string
+	torrents, err := provider.withMirrors(context.Background(), func(mirror string) ([]*Torrent, error) {
+		visited = append(visited, mirror)
+		if mirror == "https://parked.mirror.test" {
+			return nil, errNoMatches
+		}
+		return []*Torrent{{Mirror: mirror}}, nil
+	})
+	if err != nil || len(torrents) != 1 || torrents[0].Mirror != "https://live.mirror.test" {
+		t.Fatalf("expected the live mirror to answer, got %v, %v", torrents, err)
+	}
+	if len(visited) != 2 {
+		t.Fatalf("expected both mirrors to be tried, got %v", visited)
+	}
+	// the parked mirror failed so the fastest policy tries the live one first
+	if mirrors := provider.mirrors(); mirrors[0] != "https://live.mirror.test" {
+		t.Fatalf("expected the parked mirror to be demoted, got %v", mirrors)
+	}
+}
+
+func TestMirrorsWithoutMatchesAnywhere(t *testing.T) {
+	provider := newMirrorProvider("empty", "https://empty-a.mirror.test", "https://empty-b.mirror.test")
+	torrents, err := provider.withMirrors(context.Background(), func(mirror string) ([]*Torrent, error) {
+		return nil, errNoMatches
+	})
+	if err != nil || len(torrents) != 0 {
+		t.Fatalf("expected an empty result when no mirror matched, got %v, %v", torrents, err)
+	}
+
+	down := errors.New("connection refused")
+	provider = newMirrorProvider("mixed", "https://empty-c.mirror.test", "https://down.mirror.test")
+	_, err = provider.withMirrors(context.Background(), func(mirror string) ([]*Torrent, error) {
+		if mirror == "https://down.mirror.test" {
+			return nil, down
+		}
+		return nil, errNoMatches
+	})
+	if !errors.Is(err, down) {
+		t.Fatalf("expected the error of the failing mirror, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package providers
+
+import (
+	"time"
+)
+
+// SearchRecord describes a finished search, Torrents are the ranked results
+// before pagination while Seen holds every torrent the providers answered,
+// before deduplication and filters. Provider is empty for searches over every
+// provider.
+type SearchRecord struct {
+	Params    SearchParams
+	Provider  string
+	StartedAt time.Time
+	Duration  time.Duration
+	Providers []*ProviderStatus
+	Torrents  []*Torrent
+	Seen      []*Torrent
+}
+
+// SearchObserver is told about every search, ObserveSearch runs on the request
+// path so slow work belongs in the observer's own goroutine.
+type SearchObserver interface {
+	ObserveSearch(record *SearchRecord)
+}
+
+func (p *TorrentManager) AddObserver(observer SearchObserver) {
+	p.observersMu.Lock()
+	defer p.observersMu.Unlock()
+	p.observers = append(p.observers, observer)
+}
+
+// observedTorrents copies the torrents of a fan-out before postFilter merges
+// duplicates into them, it returns nil while nobody observes searches.
+func (p *TorrentManager) observedTorrents(torrents []*Torrent) []*Torrent {
+	p.observersMu.RLock()
+	observed := len(p.observers) > 0
+	p.observersMu.RUnlock()
+	if !observed {
+		return nil
+	}
+	seen := make([]*Torrent, 0, len(torrents))
+	for _, torrent := range torrents {
+		copied := *torrent
+		copied.Sources = append([]string(nil), torrent.Sources...)
+		seen = append(seen, &copied)
+	}
+	return seen
+}
+
+func (p *TorrentManager) observe(provider string, params SearchParams, started time.Time, result *SearchResult, seen []*Torrent) {
+	p.observersMu.RLock()
+	observers := p.observers
+	p.observersMu.RUnlock()
+	if len(observers) == 0 {
+		return
+	}
+
+	record := &SearchRecord{
+		Params:    params,
+		Provider:  provider,
+		StartedAt: started,
+		Duration:  time.Since(started),
+		Providers: result.Providers,
+		Torrents:  result.Torrents,
+		Seen:      seen,
+	}
+	for _, observer := range observers {
+		observer.ObserveSearch(record)
+	}
+}
@@ -0,0 +1,57 @@
+package providers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pagination tells providers how to reach results past the first page, either
+// by following NextPageSelector or by filling {page} in PageUrl. The first page
+// is always SearchUrl, page n >= 2 replaces {page} with n+PageOffset.
+type Pagination struct {
+	NextPageSelector string `json:"nextPageSelector,omitempty"`
+	PageUrl          string `json:"pageUrl,omitempty"`
+	PageOffset       int    `json:"pageOffset,omitempty"`
+	MaxPages         int    `json:"maxPages"`
+}
+
+func (t *TorrentProvider) maxPages() int {
+	if t.config.Pagination == nil || t.config.Pagination.MaxPages < 1 {
+		return 1
+	}
+	return t.config.Pagination.MaxPages
+}
+
+// pageUrl returns the url of a page from the configured template, or an empty
+// string when pages are not reachable by template.
+func (t *TorrentProvider) pageUrl(baseUrl string, params SearchParams, page int) string {
+	if page == 1 {
+		return t.searchUrl(baseUrl, params)
+	}
+	if t.config.Pagination == nil || t.config.Pagination.PageUrl == "" {
+		return ""
+	}
+	path := strings.Replace(t.config.Pagination.PageUrl, "{query}", params.Query, 1)
+	path = strings.Replace(path, "{page}", strconv.Itoa(page+t.config.Pagination.PageOffset), 1)
+	return fmt.Sprintf("%s%s", baseUrl, path)
+}
+
+// Paginate returns the requested page of items, a zero limit returns every item.
+func Paginate(items []*Torrent, page int, limit int) []*Torrent {
+	if limit <= 0 {
+		return items
+	}
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * limit
+	if start >= len(items) {
+		return []*Torrent{}
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
@@ -0,0 +1,67 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+type Capabilities struct {
+	Movies     bool `json:"movies"`
+	Series     bool `json:"series"`
+	ImdbSearch bool `json:"imdbSearch"`
+}
+
+type Provider interface {
+	Name() string
+	Search(ctx context.Context, params SearchParams) ([]*Torrent, error)
+	Capabilities() Capabilities
+	Health(ctx context.Context) error
+}
+
+// StreamingProvider is implemented by providers able to hand out each torrent
+// as soon as it is found, emit may be called from several goroutines.
+type StreamingProvider interface {
+	SearchStream(ctx context.Context, params SearchParams, emit func(*Torrent)) ([]*Torrent, error)
+}
+
+// ProviderFactory builds a provider for a config whose type it was registered with.
+type ProviderFactory func(config *ProviderConfig, logger *zerolog.Logger) (Provider, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]ProviderFactory{}
+)
+
+func init() {
+	RegisterProvider("html", newHtmlProvider)
+	RegisterProvider("api", newApiProvider)
+}
+
+func RegisterProvider(providerType string, factory ProviderFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[providerType]; ok {
+		panic(fmt.Sprintf("provider type %s already registered", providerType))
+	}
+	registry[providerType] = factory
+}
+
+func IsRegistered(providerType string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, ok := registry[providerType]
+	return ok
+}
+
+func NewProvider(config *ProviderConfig, logger *zerolog.Logger) (Provider, error) {
+	registryMu.RLock()
+	factory, ok := registry[config.Type]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q for provider %s", config.Type, config.Name)
+	}
+	return factory(config, logger)
+}
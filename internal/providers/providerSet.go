@@ -0,0 +1,178 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultProvidersReloadInterval = 10 * time.Second
+
+// providerSet is an immutable snapshot of the validated provider configs and
+// filter profiles, it is swapped as a whole on reload.
+type providerSet struct {
+	configs  []*ProviderConfig
+	byFile   map[string]*ProviderConfig
+	sources  map[string][]string
+	profiles *FilterProfiles
+	loadedAt time.Time
+}
+
+// get looks a provider up by config file basename, then by name.
+func (s *providerSet) get(name string) *ProviderConfig {
+	_, cfg := s.lookup(name)
+	return cfg
+}
+
+// lookup returns the config file basename of a provider along with its config.
+func (s *providerSet) lookup(name string) (string, *ProviderConfig) {
+	if cfg, ok := s.byFile[name]; ok {
+		return name, cfg
+	}
+	for file, cfg := range s.byFile {
+		if strings.EqualFold(cfg.Name, name) {
+			return file, cfg
+		}
+	}
+	return "", nil
+}
+
+func (p *TorrentManager) providers() *providerSet {
+	return p.set.Load()
+}
+
+// loadProviderSet reads every config layer, the returned error is a
+// ValidationErrors when any config is invalid.
+func (p *TorrentManager) loadProviderSet() (*providerSet, error) {
+	documents, problems, err := p.providerDocuments()
+	if err != nil {
+		return nil, err
+	}
+	set := &providerSet{
+		byFile:   make(map[string]*ProviderConfig),
+		sources:  make(map[string][]string),
+		loadedAt: time.Now(),
+	}
+	names := make(map[string]string)
+	for _, name := range sortedDocumentNames(documents) {
+		document := documents[name]
+		providerConfig, err := p.parseProviderConfig(document)
+		if err != nil {
+			problems = append(problems, asValidationErrors(document.source(), err)...)
+			continue
+		}
+		key := strings.ToLower(providerConfig.Name)
+		if previous, ok := names[key]; ok {
+			problems = append(problems, &ValidationError{File: document.source(), Field: "name", Message: fmt.Sprintf("provider name %q is already used by %s", providerConfig.Name, previous)})
+			continue
+		}
+		names[key] = document.source()
+		set.configs = append(set.configs, providerConfig)
+		set.byFile[name] = providerConfig
+		set.sources[name] = document.sources
+	}
+
+	profiles, err := p.loadFilterProfiles()
+	if err != nil {
+		problems = append(problems, asValidationErrors(filterProfilesFile, err)...)
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	set.profiles = profiles
+	return set, nil
+}
+
+// Reload loads and validates the configs again, the current set is only
+// replaced when everything is valid.
+func (p *TorrentManager) Reload() error {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+	return p.reload()
+}
+
+// reload is Reload for callers already holding reloadMu.
+func (p *TorrentManager) reload() error {
+	set, err := p.loadProviderSet()
+	if err != nil {
+		return err
+	}
+	p.set.Store(set)
+	p.logger.Info().Msgf("loaded %d provider configs", len(set.configs))
+	return nil
+}
+
+// WatchProviders polls TAG_PROVIDERS_DIR and reloads the configs whenever a
+// file changes, polling also catches the symlink swaps of mounted configmaps.
+// It returns once ctx is done.
+func (p *TorrentManager) WatchProviders(ctx context.Context) {
+	if p.config.ProvidersDir == "" {
+		return
+	}
+	interval := p.config.ProvidersReloadInterval
+	if interval <= 0 {
+		interval = defaultProvidersReloadInterval
+	}
+	last, err := dirFingerprint(p.config.ProvidersDir)
+	if err != nil {
+		p.logger.Err(err).Msgf("error while reading providers dir %s: %v", p.config.ProvidersDir, err)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		fingerprint, err := dirFingerprint(p.config.ProvidersDir)
+		if err != nil {
+			p.logger.Err(err).Msgf("error while reading providers dir %s: %v", p.config.ProvidersDir, err)
+			continue
+		}
+		if fingerprint == last {
+			continue
+		}
+		// a broken change is reported once and retried on the next edit
+		last = fingerprint
+		if err := p.Reload(); err != nil {
+			p.logger.Err(err).Msgf("keeping previous provider configs, reload failed: %v", err)
+		}
+	}
+}
+
+// dirFingerprint hashes the names and contents of every file under dir.
+func dirFingerprint(dir string) (string, error) {
+	hash := sha1.New()
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// configmaps keep older versions in hidden directories
+		if entry.IsDir() && path != dir && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || !isConfigFile(entry.Name()) {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		io.WriteString(hash, path)
+		_, err = io.Copy(hash, file)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
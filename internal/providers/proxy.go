@@ -0,0 +1,96 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ProxyConfig routes a provider through http(s) or socks5 proxies, several
+// urls are used in turn. Direct ignores the global TAG_PROXIES default.
+type ProxyConfig struct {
+	Urls   []string `json:"urls,omitempty"`
+	Direct bool     `json:"direct,omitempty"`
+}
+
+// proxyRotator owns the transport of its provider so collectors of every
+// search share the same connection pool.
+type proxyRotator struct {
+	urls []*url.URL
+	next uint64
+	http *http.Transport
+}
+
+func (r *proxyRotator) proxy(*http.Request) (*url.URL, error) {
+	n := atomic.AddUint64(&r.next, 1) - 1
+	return r.urls[n%uint64(len(r.urls))], nil
+}
+
+// proxyRotators keep rotating across searches since providers are built per
+// search, they are keyed by provider name and replaced when the urls change.
+var (
+	proxyRotatorsMu sync.Mutex
+	proxyRotators   = make(map[string]*proxyRotator)
+)
+
+func parseProxyUrl(raw string) (*url.URL, error) {
+	proxyUrl, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch proxyUrl.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyUrl.Scheme)
+	}
+	if proxyUrl.Host == "" {
+		return nil, fmt.Errorf("proxy %s has no host", proxyUrl.Redacted())
+	}
+	return proxyUrl, nil
+}
+
+// proxyRotatorFor returns nil when the provider goes direct.
+func proxyRotatorFor(config *ProviderConfig) *proxyRotator {
+	if config.Proxy == nil || config.Proxy.Direct || len(config.Proxy.Urls) == 0 {
+		return nil
+	}
+	key := strings.Join(config.Proxy.Urls, ",")
+
+	proxyRotatorsMu.Lock()
+	defer proxyRotatorsMu.Unlock()
+	if rotator, ok := proxyRotators[config.Name]; ok && rotator.key() == key {
+		return rotator
+	}
+	rotator := &proxyRotator{}
+	for _, raw := range config.Proxy.Urls {
+		// urls are validated when the config is read
+		if proxyUrl, err := parseProxyUrl(raw); err == nil {
+			rotator.urls = append(rotator.urls, proxyUrl)
+		}
+	}
+	if len(rotator.urls) == 0 {
+		return nil
+	}
+	rotator.http = http.DefaultTransport.(*http.Transport).Clone()
+	rotator.http.Proxy = rotator.proxy
+	if previous, ok := proxyRotators[config.Name]; ok {
+		previous.http.CloseIdleConnections()
+	}
+	proxyRotators[config.Name] = rotator
+	return rotator
+}
+
+func (r *proxyRotator) key() string {
+	var urls []string
+	for _, proxyUrl := range r.urls {
+		urls = append(urls, proxyUrl.String())
+	}
+	return strings.Join(urls, ",")
+}
+
+func (r *proxyRotator) transport() *http.Transport {
+	return r.http
+}
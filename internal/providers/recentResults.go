@@ -0,0 +1,51 @@
+package providers
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/xochilpili/torrent-api-go/internal/cache"
+)
+
+const recentResultsSize = 2000
+
+// recentResults remembers the torrents returned by the latest searches by
+// info hash, so clients can refer to a result by id instead of its magnet.
+type recentResults struct {
+	backend cache.Backend
+}
+
+func newRecentResults() *recentResults {
+	return &recentResults{backend: cache.NewMemory(recentResultsSize)}
+}
+
+func (r *recentResults) add(torrents []*Torrent) {
+	for _, torrent := range torrents {
+		if torrent.InfoHash == "" {
+			continue
+		}
+		data, err := json.Marshal(torrent)
+		if err != nil {
+			continue
+		}
+		r.backend.Set(torrent.InfoHash, &cache.Entry{Value: data, StoredAt: time.Now()})
+	}
+}
+
+func (r *recentResults) get(id string) (*Torrent, bool) {
+	entry, ok := r.backend.Get(strings.ToLower(id))
+	if !ok {
+		return nil, false
+	}
+	var torrent Torrent
+	if err := json.Unmarshal(entry.Value, &torrent); err != nil {
+		return nil, false
+	}
+	return &torrent, true
+}
+
+// RecentResult returns a torrent of a previous search by its info hash.
+func (p *TorrentManager) RecentResult(id string) (*Torrent, bool) {
+	return p.recent.get(id)
+}
@@ -0,0 +1,168 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidSort = errors.New("invalid sort")
+
+var repackRegex = regexp.MustCompile(`(?i)\b(repack|proper)\b`)
+
+// ScoringWeights assigns points to each release attribute, maps are keyed by
+// the lowercased value without dashes or spaces (web-dl becomes webdl).
+type ScoringWeights struct {
+	Resolution      map[string]float64 `json:"resolution,omitempty"`
+	Quality         map[string]float64 `json:"quality,omitempty"`
+	Codec           map[string]float64 `json:"codec,omitempty"`
+	PreferredGroups []string           `json:"preferredGroups,omitempty"`
+	Group           *float64           `json:"group,omitempty"`
+	Seeds           *float64           `json:"seeds,omitempty"`
+	TargetSize      map[string]string  `json:"targetSize,omitempty"`
+	Size            *float64           `json:"size,omitempty"`
+	RepackProper    *float64           `json:"repackProper,omitempty"`
+
+	targetSize map[string]int64
+}
+
+func defaultScoringWeights() *ScoringWeights {
+	return &ScoringWeights{
+		Resolution: map[string]float64{"2160p": 40, "1080p": 30, "720p": 15, "480p": 0},
+		Quality: map[string]float64{
+			"bluray": 30, "webdl": 25, "web": 25, "webrip": 20, "brrip": 15, "bdrip": 15,
+			"hdrip": 10, "hdtv": 10, "dvdrip": 5, "dvdscr": -30, "scr": -30,
+			"tc": -40, "ts": -50, "telesync": -50, "cam": -60, "hdcam": -60,
+		},
+		Codec:        map[string]float64{"x265": 10, "h265": 10, "hevc": 10, "x264": 5, "h264": 5, "avc": 5},
+		Group:        floatPtr(15),
+		Seeds:        floatPtr(10),
+		TargetSize:   map[string]string{"movie": "2 GB", "serie": "700 MB"},
+		Size:         floatPtr(20),
+		RepackProper: floatPtr(5),
+	}
+}
+
+// merge fills every weight left unset in the profile with the defaults.
+func (s *ScoringWeights) merge(defaults *ScoringWeights) *ScoringWeights {
+	if s == nil {
+		return defaults
+	}
+	merged := *s
+	if merged.Resolution == nil {
+		merged.Resolution = defaults.Resolution
+	}
+	if merged.Quality == nil {
+		merged.Quality = defaults.Quality
+	}
+	if merged.Codec == nil {
+		merged.Codec = defaults.Codec
+	}
+	if merged.Group == nil {
+		merged.Group = defaults.Group
+	}
+	if merged.Seeds == nil {
+		merged.Seeds = defaults.Seeds
+	}
+	if merged.TargetSize == nil {
+		merged.TargetSize = defaults.TargetSize
+	}
+	if merged.Size == nil {
+		merged.Size = defaults.Size
+	}
+	if merged.RepackProper == nil {
+		merged.RepackProper = defaults.RepackProper
+	}
+	return &merged
+}
+
+func (s *ScoringWeights) parse() error {
+	s.targetSize = make(map[string]int64)
+	for itemType, size := range s.TargetSize {
+		bytes, err := SizeToBytes(size)
+		if err != nil {
+			return fmt.Errorf("invalid target size for %s: %w", itemType, err)
+		}
+		s.targetSize[itemType] = bytes
+	}
+	return nil
+}
+
+// score sets the score of a torrent and the points given by each factor.
+func (s *ScoringWeights) score(item *Torrent) {
+	breakdown := make(map[string]float64)
+	breakdown["resolution"] = s.Resolution[normalizeScoringKey(item.Resolution)]
+	breakdown["quality"] = s.Quality[normalizeScoringKey(item.Quality)]
+	breakdown["codec"] = s.Codec[normalizeScoringKey(item.Codec)]
+
+	if matchesAny(item.Group, s.PreferredGroups, normalizeGroup) {
+		breakdown["group"] = *s.Group
+	}
+
+	breakdown["seeds"] = *s.Seeds * math.Log10(float64(item.Seeds)+1)
+
+	if target, ok := s.targetSize[item.Type]; ok && target > 0 && item.SizeBytes > 0 {
+		distance := math.Abs(float64(item.SizeBytes-target)) / float64(target)
+		breakdown["size"] = *s.Size * math.Max(0, 1-distance)
+	}
+
+	if repackRegex.MatchString(item.OriginalTitle) {
+		breakdown["repack_proper"] = *s.RepackProper
+	}
+
+	var score float64
+	for factor, points := range breakdown {
+		points = math.Round(points*100) / 100
+		breakdown[factor] = points
+		score += points
+	}
+	item.Score = math.Round(score*100) / 100
+	item.ScoreBreakdown = breakdown
+}
+
+func normalizeScoringKey(value string) string {
+	return strings.NewReplacer("-", "", " ", "", ".", "").Replace(strings.ToLower(value))
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+func ValidateSort(sortBy string, order string) error {
+	switch sortBy {
+	case "", "score", "seeds", "size", "peers":
+	default:
+		return fmt.Errorf("%w: unknown sort field %s", ErrInvalidSort, sortBy)
+	}
+	switch order {
+	case "", "asc", "desc":
+	default:
+		return fmt.Errorf("%w: unknown order %s", ErrInvalidSort, order)
+	}
+	return nil
+}
+
+func sortTorrents(items []*Torrent, sortBy string, order string) {
+	less := func(i, j int) bool {
+		switch sortBy {
+		case "seeds":
+			return items[i].Seeds < items[j].Seeds
+		case "peers":
+			return items[i].Peers < items[j].Peers
+		case "size":
+			return items[i].SizeBytes < items[j].SizeBytes
+		default:
+			return items[i].Score < items[j].Score
+		}
+	}
+	if order == "asc" {
+		sort.SliceStable(items, less)
+		return
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return less(j, i)
+	})
+}
@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xochilpili/torrent-api-go/internal/cache"
+	"github.com/xochilpili/torrent-api-go/internal/config"
+)
+
+// searchCache keeps raw provider results, entries younger than ttl are fresh,
+// entries younger than ttl+staleTtl are served while a refresh runs in background.
+type searchCache struct {
+	backend    cache.Backend
+	ttl        time.Duration
+	staleTtl   time.Duration
+	mu         sync.Mutex
+	refreshing map[string]bool
+}
+
+type cachedSearch struct {
+	torrents []*Torrent
+	age      time.Duration
+	stale    bool
+}
+
+func newSearchCache(cfg *config.Config) (*searchCache, error) {
+	if !cfg.CacheEnabled {
+		return nil, nil
+	}
+
+	var backend cache.Backend
+	switch cfg.CacheBackend {
+	case "memory":
+		backend = cache.NewMemory(cfg.CacheSize)
+	case "disk":
+		disk, err := cache.NewDisk(cfg.CacheDir)
+		if err != nil {
+			return nil, err
+		}
+		backend = disk
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.CacheBackend)
+	}
+
+	return &searchCache{
+		backend:    backend,
+		ttl:        cfg.CacheTTL,
+		staleTtl:   cfg.CacheStaleTTL,
+		refreshing: make(map[string]bool),
+	}, nil
+}
+
+func searchCacheKey(provider string, query string) string {
+	if unescaped, err := url.PathUnescape(query); err == nil {
+		query = unescaped
+	}
+	query = strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	return fmt.Sprintf("%s|%s", strings.ToLower(provider), query)
+}
+
+func (s *searchCache) get(key string) (*cachedSearch, bool) {
+	entry, ok := s.backend.Get(key)
+	if !ok {
+		return nil, false
+	}
+	age := entry.Age()
+	if age > s.ttl+s.staleTtl {
+		s.backend.Delete(key)
+		return nil, false
+	}
+
+	var torrents []*Torrent
+	if err := json.Unmarshal(entry.Value, &torrents); err != nil {
+		s.backend.Delete(key)
+		return nil, false
+	}
+	return &cachedSearch{torrents: torrents, age: age, stale: age > s.ttl}, true
+}
+
+func (s *searchCache) set(key string, torrents []*Torrent) error {
+	data, err := json.Marshal(torrents)
+	if err != nil {
+		return err
+	}
+	return s.backend.Set(key, &cache.Entry{Value: data, StoredAt: time.Now()})
+}
+
+// startRefresh reports whether the caller owns the background refresh for key.
+func (s *searchCache) startRefresh(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refreshing[key] {
+		return false
+	}
+	s.refreshing[key] = true
+	return true
+}
+
+func (s *searchCache) endRefresh(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.refreshing, key)
+}
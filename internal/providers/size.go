@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var sizeRegex = regexp.MustCompile(`^([0-9][0-9.,' ]*)\s*([a-zA-Z]*)$`)
+
+// sites label binary sizes with SI units (a "GB" is 1024 MB on all of them),
+// so both spellings use powers of 1024.
+var sizeUnits = map[string]int64{
+	"":      1,
+	"b":     1,
+	"byte":  1,
+	"bytes": 1,
+	"o":     1,
+	"k":     1 << 10,
+	"kb":    1 << 10,
+	"kib":   1 << 10,
+	"ko":    1 << 10,
+	"m":     1 << 20,
+	"mb":    1 << 20,
+	"mib":   1 << 20,
+	"mo":    1 << 20,
+	"g":     1 << 30,
+	"gb":    1 << 30,
+	"gib":   1 << 30,
+	"go":    1 << 30,
+	"t":     1 << 40,
+	"tb":    1 << 40,
+	"tib":   1 << 40,
+	"to":    1 << 40,
+	"pb":    1 << 50,
+	"pib":   1 << 50,
+}
+
+// SizeToBytes parses human readable sizes like "1.4 GiB", "1,234.5 MB",
+// "1.234,5 MB" or "700MB" into bytes.
+func SizeToBytes(sizeStr string) (int64, error) {
+	sizeStr = strings.TrimSpace(strings.NewReplacer("\u00a0", " ", "\u202f", " ").Replace(sizeStr))
+	matches := sizeRegex.FindStringSubmatch(sizeStr)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid size %q", sizeStr)
+	}
+
+	size, err := parseSizeNumber(matches[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", sizeStr, err)
+	}
+
+	multiplier, ok := sizeUnits[strings.ToLower(matches[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid unit %s", matches[2])
+	}
+	return int64(size * float64(multiplier)), nil
+}
+
+// parseSizeNumber accepts both dot and comma decimal separators, the last
+// separator is the decimal one unless it is repeated or groups three digits.
+func parseSizeNumber(number string) (float64, error) {
+	number = strings.NewReplacer(" ", "", "'", "").Replace(number)
+	lastDot := strings.LastIndex(number, ".")
+	lastComma := strings.LastIndex(number, ",")
+
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastComma > lastDot {
+			number = strings.ReplaceAll(number, ".", "")
+			number = strings.Replace(number, ",", ".", 1)
+		} else {
+			number = strings.ReplaceAll(number, ",", "")
+		}
+	case lastComma >= 0:
+		if strings.Count(number, ",") > 1 || len(number)-lastComma-1 == 3 {
+			number = strings.ReplaceAll(number, ",", "")
+		} else {
+			number = strings.Replace(number, ",", ".", 1)
+		}
+	case lastDot >= 0:
+		if strings.Count(number, ".") > 1 {
+			number = strings.ReplaceAll(number, ".", "")
+		}
+	}
+	return strconv.ParseFloat(number, 64)
+}
+
+func FormatSize(size int64) string {
+	const (
+		MB = 1024 * 1024
+		GB = 1024 * 1024 * 1024
+	)
+	if size >= GB {
+		return fmt.Sprintf("%.2f GB", float64(size)/GB)
+	}
+	return fmt.Sprintf("%.2f MB", float64(size)/MB)
+}
@@ -0,0 +1,39 @@
+package providers
+
+import "testing"
+
+func TestSizeToBytes(t *testing.T) {
+	tests := []struct {
+		size     string
+		expected int64
+	}{
+		{size: "1.4 GiB", expected: 1503238553},
+		{size: "1,234.5 MB", expected: 1294467072},
+		{size: "1.234,5 MB", expected: 1294467072},
+		{size: "1 234,5 MB", expected: 1294467072},
+		{size: "700MB", expected: 734003200},
+		{size: "700 Mo", expected: 734003200},
+		{size: "1,5 TB", expected: 1649267441664},
+		{size: "2.5 TiB", expected: 2748779069440},
+		{size: "1,024 KB", expected: 1048576},
+		{size: "512", expected: 512},
+	}
+	for _, test := range tests {
+		size, err := SizeToBytes(test.size)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.size, err)
+			continue
+		}
+		if size != test.expected {
+			t.Errorf("%q: expected %d bytes, got %d", test.size, test.expected, size)
+		}
+	}
+}
+
+func TestSizeToBytesInvalid(t *testing.T) {
+	for _, size := range []string{"1.4 XB", "GB", "", "1.4.5,6,7 GB MB"} {
+		if bytes, err := SizeToBytes(size); err == nil {
+			t.Errorf("%q: expected an error, got %d bytes", size, bytes)
+		}
+	}
+}
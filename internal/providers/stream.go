@@ -0,0 +1,65 @@
+package providers
+
+import "sync"
+
+const (
+	StreamEventTorrent = "torrent"
+	StreamEventDone    = "done"
+	StreamEventError   = "error"
+	StreamEventSummary = "summary"
+)
+
+// StreamEvent is handed out while a streamed search runs, Event tells which
+// of the other fields is set.
+type StreamEvent struct {
+	Event    string          `json:"-"`
+	Provider string          `json:"provider,omitempty"`
+	Torrent  *Torrent        `json:"torrent,omitempty"`
+	Status   *ProviderStatus `json:"status,omitempty"`
+	Result   *SearchResult   `json:"result,omitempty"`
+}
+
+// searchStream serializes events to the caller until it is closed, events
+// from providers still running after that are dropped.
+type searchStream struct {
+	mu      sync.Mutex
+	closed  bool
+	emit    func(*StreamEvent)
+	keep    func(*Torrent) bool
+	scoring *ScoringWeights
+}
+
+func (s *searchStream) send(event *StreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.emit(event)
+}
+
+func (s *searchStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+// torrent emits a scored copy of the item when it passes the filters, the
+// original keeps going through dedupe and ranking for the summary.
+func (s *searchStream) torrent(item *Torrent) {
+	normalizeTorrent(item)
+	if !s.keep(item) {
+		return
+	}
+	streamed := *item
+	s.scoring.score(&streamed)
+	s.send(&StreamEvent{Event: StreamEventTorrent, Provider: item.Provider, Torrent: &streamed})
+}
+
+func (s *searchStream) status(status *ProviderStatus) {
+	event := StreamEventDone
+	if status.Status != ProviderStatusOk {
+		event = StreamEventError
+	}
+	s.send(&StreamEvent{Event: event, Provider: status.Provider, Status: status})
+}
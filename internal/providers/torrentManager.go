@@ -3,29 +3,68 @@ package providers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/fs"
-	"os"
-	"path/filepath"
-	"sort"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog"
+	"github.com/xochilpili/torrent-api-go/internal/config"
+	"github.com/xochilpili/torrent-api-go/internal/metrics"
 )
 
+var (
+	ErrUnknownProfile      = errors.New("unknown filter profile")
+	ErrUnknownProvider     = errors.New("unknown provider")
+	ErrProviderUnavailable = errors.New("provider unavailable")
+)
+
+// ProviderUnavailableError is returned when the only searched provider failed,
+// timed out or was skipped by its circuit breaker. RetryAfter is the remaining
+// cool-down of an open breaker.
+type ProviderUnavailableError struct {
+	Status     *ProviderStatus
+	RetryAfter time.Duration
+}
+
+func (e *ProviderUnavailableError) Error() string {
+	return fmt.Sprintf("provider %s failed with status %s: %s", e.Status.Provider, e.Status.Status, e.Status.Error)
+}
+
+func (e *ProviderUnavailableError) Unwrap() error {
+	return ErrProviderUnavailable
+}
+
 type TorrentManager struct {
-	logger *zerolog.Logger
+	config   *config.Config
+	logger   *zerolog.Logger
+	cache    *searchCache
+	set      atomic.Pointer[providerSet]
+	health   *healthTracker
+	breakers *circuitBreakers
+	recent   *recentResults
+
+	observersMu sync.RWMutex
+	observers   []SearchObserver
+
+	// reloadMu makes every load and swap of set atomic with overlay writes
+	reloadMu sync.Mutex
 }
 
 type ProviderConfig struct {
-	Name          string `json:"name"`
-	BaseUrl       string `json:"url"`
-	SearchUrl     string `json:"searchUrl"`
-	Enabled       bool   `json:"enabled"`
-	Type          string `json:"type"`
-	Debug         bool   `json:"debug"`
-	ItemSelector  string `json:"itemSelector"`
+	Name          string   `json:"name"`
+	BaseUrl       string   `json:"url"`
+	Mirrors       []string `json:"mirrors,omitempty"`
+	MirrorPolicy  string   `json:"mirrorPolicy,omitempty"`
+	SearchUrl     string   `json:"searchUrl"`
+	Enabled       bool     `json:"enabled"`
+	Type          string   `json:"type"`
+	Debug         bool     `json:"debug"`
+	Timeout       string   `json:"timeout,omitempty"`
+	ItemSelector  string   `json:"itemSelector"`
 	ItemsSelector struct {
 		DetailUrl         string `json:"detail_url"`
 		Title             string `json:"title"`
@@ -35,205 +74,321 @@ type ProviderConfig struct {
 		MagnetPreffixLink string `json:"magnetPreffixLink"`
 		MagnetSelector    string `json:"magnetSelector"`
 	} `json:"itemsSelector"`
-	Trackers []string `json:"trackers,omitempty"`
+	Pagination   *Pagination    `json:"pagination,omitempty"`
+	Api          *ApiMapping    `json:"api,omitempty"`
+	Capabilities *Capabilities  `json:"capabilities,omitempty"`
+	Trackers     []string       `json:"trackers,omitempty"`
+	Canary       string         `json:"canary,omitempty"`
+	Breaker      *BreakerConfig `json:"breaker,omitempty"`
+	Proxy        *ProxyConfig   `json:"proxy,omitempty"`
 }
 
-func NewTorrentManager(logger *zerolog.Logger) *TorrentManager {
-	return &TorrentManager{
-		logger: logger,
+func (c *ProviderConfig) SearchTimeout() time.Duration {
+	timeout, err := time.ParseDuration(c.Timeout)
+	if err != nil || timeout <= 0 {
+		return defaultProviderTimeout
 	}
+	return timeout
 }
 
-func (p *TorrentManager) readConfigFile(file string) (*ProviderConfig, error) {
-	var config ProviderConfig
-	configFile, err := os.Open(file)
+func NewTorrentManager(config *config.Config, logger *zerolog.Logger) (*TorrentManager, error) {
+	cache, err := newSearchCache(config)
 	if err != nil {
 		return nil, err
 	}
-	defer configFile.Close()
-	jsonParser := json.NewDecoder(configFile)
-	jsonParser.Decode(&config)
+	manager := &TorrentManager{
+		config:   config,
+		logger:   logger,
+		cache:    cache,
+		health:   newHealthTracker(config.HealthHistory),
+		breakers: newCircuitBreakers(config),
+		recent:   newRecentResults(),
+	}
+	if err := manager.Reload(); err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+// parseProviderConfig decodes a provider document, the returned error is a
+// ValidationErrors listing every problem found.
+func (p *TorrentManager) parseProviderConfig(document *providerDocument) (*ProviderConfig, error) {
+	var config ProviderConfig
+	source := document.source()
+	problems := unknownFields(source, "", document.data, reflect.TypeOf(config))
+	raw, err := json.Marshal(document.data)
+	if err != nil {
+		return nil, append(problems, &ValidationError{File: source, Message: err.Error()})
+	}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		problem := &ValidationError{File: source, Message: err.Error()}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			problem.Field = typeErr.Field
+			problem.Message = fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)
+		}
+		return nil, append(problems, problem)
+	}
+	problems = append(problems, validateProviderConfig(source, &config)...)
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	if len(p.config.Proxies) > 0 && (config.Proxy == nil || (!config.Proxy.Direct && len(config.Proxy.Urls) == 0)) {
+		config.Proxy = &ProxyConfig{Urls: p.config.Proxies}
+	}
 	return &config, nil
 }
 
 func (p *TorrentManager) loadProviderConfig(provider string) (*ProviderConfig, error) {
-	cfg, err := p.readConfigFile(fmt.Sprintf("./internal/providers/config/%s.json", provider))
-	if err != nil {
+	cfg := p.providers().get(provider)
+	if cfg == nil {
+		err := fmt.Errorf("%w: %s", ErrUnknownProvider, provider)
 		p.logger.Err(err).Msgf("error while getting provider %s config file: %v", provider, err)
 		return nil, err
 	}
-	return cfg, err
+	return cfg, nil
 }
 
-func (p *TorrentManager) loadAllProviderConfig() ([]*ProviderConfig, error) {
+func (p *TorrentManager) GetActiveProviders() ([]*ProviderConfig, error) {
 	var config []*ProviderConfig
-	var files []string
-	err := filepath.Walk("./internal/providers/config", func(path string, info fs.FileInfo, err error) error {
-		if err != nil {
-			return err
+	for _, conf := range p.providers().configs {
+		if conf.Enabled {
+			config = append(config, conf)
 		}
+	}
+	return config, nil
+}
 
-		if !info.IsDir() {
-			files = append(files, path)
-		}
-		return nil
-	})
+func (p *TorrentManager) FetchAllActive(ctx context.Context, params SearchParams) (*SearchResult, error) {
+	cfg, err := p.GetActiveProviders()
+	if err != nil {
+		return nil, err
+	}
+	profile, err := p.searchProfile(params)
 	if err != nil {
 		return nil, err
 	}
+	started := time.Now()
+	result := p.fanOut(ctx, cfg, params, nil)
+	seen := p.observedTorrents(result.Torrents)
+	result.Torrents = p.postFilter(result.Torrents, params, profile)
+	p.observe("", params, started, result, seen)
+	p.paginate(result, params)
+	return result, nil
+}
 
-	for _, file := range files {
-		configFile, err := p.readConfigFile(file)
-		if err != nil {
-			return nil, err
-		}
-		config = append(config, configFile)
+// StreamAllActive runs the same search as FetchAllActive but hands out every
+// torrent passing the filters as soon as a provider finds it, then a done or
+// error event per provider and a summary with the ranked results. Emit calls
+// are serialized and none happens once StreamAllActive returned, errors are
+// returned before any event is emitted.
+func (p *TorrentManager) StreamAllActive(ctx context.Context, params SearchParams, emit func(*StreamEvent)) error {
+	cfg, err := p.GetActiveProviders()
+	if err != nil {
+		return err
 	}
-	return config, nil
+	profile, err := p.searchProfile(params)
+	if err != nil {
+		return err
+	}
+	stream := &searchStream{
+		emit: emit,
+		keep: func(item *Torrent) bool {
+			return p.filterItem(item, params, profile) == ""
+		},
+		scoring: profile.scoring,
+	}
+	defer stream.close()
+
+	started := time.Now()
+	result := p.fanOut(ctx, cfg, params, stream)
+	seen := p.observedTorrents(result.Torrents)
+	result.Torrents = p.postFilter(result.Torrents, params, profile)
+	p.observe("", params, started, result, seen)
+	p.paginate(result, params)
+	stream.send(&StreamEvent{Event: StreamEventSummary, Result: result})
+	return nil
 }
 
-func (p *TorrentManager) GetActiveProviders() ([]*ProviderConfig, error) {
-	var config []*ProviderConfig
-	cfg, err := p.loadAllProviderConfig()
+func (p *TorrentManager) GetProvider(provider string) (Provider, error) {
+	cfg, err := p.loadProviderConfig(provider)
 	if err != nil {
 		return nil, err
 	}
-	for _, conf := range cfg {
-		if conf.Enabled {
-			config = append(config, conf)
+	return NewProvider(cfg, p.logger)
+}
+
+func (p *TorrentManager) Capabilities(provider string) (Capabilities, error) {
+	if provider != "" {
+		torrentProvider, err := p.GetProvider(provider)
+		if err != nil {
+			return Capabilities{}, err
 		}
+		return torrentProvider.Capabilities(), nil
 	}
-	return config, nil
-}
 
-func (p *TorrentManager) FetchAllActive(ctx context.Context, params SearchParams) ([]*Torrent, error) {
-	var items []*Torrent
+	var caps Capabilities
 	cfg, err := p.GetActiveProviders()
 	if err != nil {
-		return nil, err
+		return caps, err
 	}
-	var wg sync.WaitGroup
 	for _, conf := range cfg {
-		wg.Add(1)
-		go func(ctx context.Context, conf *ProviderConfig, params SearchParams) {
-			defer wg.Done()
-			provider := NewTorrentProvider(conf, p.logger)
-			torrents := provider.FetchAndParse(ctx, params)
-			items = append(items, torrents...)
-		}(ctx, conf, params)
+		torrentProvider, err := NewProvider(conf, p.logger)
+		if err != nil {
+			return caps, err
+		}
+		providerCaps := torrentProvider.Capabilities()
+		caps.Movies = caps.Movies || providerCaps.Movies
+		caps.Series = caps.Series || providerCaps.Series
+		caps.ImdbSearch = caps.ImdbSearch || providerCaps.ImdbSearch
 	}
-	wg.Wait()
-	return p.postFilter(items, params), nil
+	return caps, nil
 }
 
-func (p *TorrentManager) FetchByProvider(ctx context.Context, provider string, params SearchParams) ([]*Torrent, error) {
-
+func (p *TorrentManager) FetchByProvider(ctx context.Context, provider string, params SearchParams) (*SearchResult, error) {
+	profile, err := p.searchProfile(params)
+	if err != nil {
+		return nil, err
+	}
 	cfg, err := p.loadProviderConfig(provider)
 	if err != nil {
 		return nil, err
 	}
-	torrentProvider := NewTorrentProvider(cfg, p.logger)
 
-	torrents := torrentProvider.FetchAndParse(ctx, params)
-	return p.postFilter(torrents, params), nil
+	started := time.Now()
+	result := p.fanOut(ctx, []*ProviderConfig{cfg}, params, nil)
+	if status := result.Providers[0]; status.Status != ProviderStatusOk {
+		return nil, &ProviderUnavailableError{Status: status, RetryAfter: status.retryAfter}
+	}
+	seen := p.observedTorrents(result.Torrents)
+	result.Torrents = p.postFilter(result.Torrents, params, profile)
+	p.observe(cfg.Name, params, started, result, seen)
+	p.paginate(result, params)
+	return result, nil
 }
 
-func (p *TorrentManager) sizeToBytes(sizeStr string) (int64, error) {
-	sizeStr = strings.TrimSpace(sizeStr)
-	var size float64
-	var unit string
+func (p *TorrentManager) Profiles() *FilterProfiles {
+	return p.providers().profiles
+}
+
+func (p *TorrentManager) Profile(name string) (*FilterProfile, error) {
+	profile := p.Profiles().Get(name)
+	if profile == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+	}
+	return profile, nil
+}
 
-	if _, err := fmt.Sscanf(sizeStr, "%f %s", &size, &unit); err != nil {
-		return 0, err
+// searchProfile validates the search params and resolves the filter profile to use.
+func (p *TorrentManager) searchProfile(params SearchParams) (*FilterProfile, error) {
+	profile, err := p.Profile(params.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateSort(params.Sort, params.Order); err != nil {
+		return nil, err
 	}
+	return profile, nil
+}
 
-	switch strings.ToUpper(unit) {
-	case "GB":
-		return int64(size * 1024 * 1024 * 1024), nil // convert gb to bytez
-	case "MB":
-		return int64(size * 1024 * 1024), nil // convert mb to bytez
-	case "KB":
-		return int64(size * 1024), nil // convert kb to bytez
-	case "B":
-		return int64(size), nil
-	default:
-		return 0, fmt.Errorf("invalid unit %s", unit)
+func (p *TorrentManager) paginate(result *SearchResult, params SearchParams) {
+	result.Total = len(result.Torrents)
+	result.Page = params.Page
+	if result.Page < 1 {
+		result.Page = 1
 	}
+	result.Limit = params.Limit
+	result.Torrents = Paginate(result.Torrents, result.Page, result.Limit)
 }
 
-func (p *TorrentManager) postFilter(items []*Torrent, params SearchParams) []*Torrent {
+func (p *TorrentManager) postFilter(items []*Torrent, params SearchParams, profile *FilterProfile) []*Torrent {
 	var filtered []*Torrent
-	p.logger.Info().Msgf("Total items received to be filtered: %d", len(items))
-	minSize, _ := p.sizeToBytes("700 MB")
-	maxSize, _ := p.sizeToBytes("3 GB")
-	minSerieSize, _ := p.sizeToBytes("250 MB")
-	maxSerieSize, _ := p.sizeToBytes("1.5 GB")
+	p.logger.Info().Msgf("Total items received to be filtered: %d, profile: %s", len(items), profile.Name)
+	if !params.KeepDuplicates {
+		received := len(items)
+		items = p.dedupe(items)
+		metrics.FilterDrops.WithLabelValues("duplicate").Add(float64(received - len(items)))
+	}
 
-	// filtering by params and size (default)
 	for _, item := range items {
-		sizeInBytes, err := p.sizeToBytes(item.Size)
-		if err != nil {
-			p.logger.Info().Msgf("error while casting size: %s, item: %s", err.Error(), item.Title)
-			continue
-		}
-		if params.Filters.Resolution != "" && !strings.Contains(strings.ToLower(item.Resolution), strings.ToLower(params.Filters.Resolution)) {
-			p.logger.Info().Msgf("skipping %s no resolution matched with %s", item.Title, params.Filters.Resolution)
+		if reason := p.filterItem(item, params, profile); reason != "" {
+			metrics.FilterDrops.WithLabelValues(reason).Inc()
 			continue
 		}
+		metrics.Results.WithLabelValues(item.Provider, "kept").Inc()
+		filtered = append(filtered, item)
+	}
 
-		if params.Filters.Group != "" && !strings.Contains(item.Group, params.Filters.Group) && !strings.Contains(strings.ToLower(item.OriginalTitle), params.Filters.Group) {
-			p.logger.Info().Msgf("skipping %s no group matched with %s", item.Title, params.Filters.Group)
-			continue
-		}
+	for _, item := range filtered {
+		profile.scoring.score(item)
+	}
+	sortTorrents(filtered, params.Sort, params.Order)
+	p.recent.add(filtered)
 
-		if !strings.EqualFold(item.Title, params.Filters.Title) && !strings.EqualFold(item.OriginalTitle, params.Filters.Title) {
-			p.logger.Info().Msgf("skipping %s no title matched with %s", item.Title, params.Filters.Title)
-			continue
-		}
+	p.logger.Info().Msgf("Total filtered: %d", len(filtered))
+	return filtered
+}
 
-		if strings.EqualFold(item.Quality, "HDCAM") {
-			p.logger.Info().Msgf("skipping %s found hdcam", item.Title)
-			continue
-		}
+// filterItem returns the reason why an item is dropped, or an empty string when it is kept.
+func (p *TorrentManager) filterItem(item *Torrent, params SearchParams, profile *FilterProfile) string {
+	if params.Filters.Resolution != "" && !strings.Contains(strings.ToLower(item.Resolution), strings.ToLower(params.Filters.Resolution)) {
+		p.logger.Info().Msgf("skipping %s no resolution matched with %s", item.Title, params.Filters.Resolution)
+		return "resolution"
+	}
 
-		switch item.Type {
-		case "movie":
-			if sizeInBytes < minSize || sizeInBytes > maxSize {
-				p.logger.Info().Msgf("skipping %s no size matched", item.Size)
-				continue
-			}
-
-		case "serie":
-			if sizeInBytes < minSerieSize || sizeInBytes > maxSerieSize {
-				p.logger.Info().Msgf("skipping %s no size matched", item.Size)
-				continue
-			}
-			if item.Season != params.Filters.Season && item.Episode != params.Filters.Episode {
-				p.logger.Info().Msgf("skipping %s no season or episode matched", item.Size)
-				continue
-			}
-		}
+	if params.Filters.Group != "" && !strings.Contains(item.Group, params.Filters.Group) && !strings.Contains(strings.ToLower(item.OriginalTitle), params.Filters.Group) {
+		p.logger.Info().Msgf("skipping %s no group matched with %s", item.Title, params.Filters.Group)
+		return "group"
+	}
 
-		filtered = append(filtered, item)
+	if params.Filters.Title != "" && !strings.EqualFold(item.Title, params.Filters.Title) && !strings.EqualFold(item.OriginalTitle, params.Filters.Title) {
+		p.logger.Info().Msgf("skipping %s no title matched with %s", item.Title, params.Filters.Title)
+		return "title"
 	}
 
-	// sort by size
-	sort.Slice(filtered, func(i, j int) bool {
-		sizeI, errI := p.sizeToBytes(filtered[i].Size)
-		sizeJ, errJ := p.sizeToBytes(filtered[j].Size)
-		if errI != nil || errJ != nil {
-			return false
+	if item.Type == "serie" && item.Season != params.Filters.Season && item.Episode != params.Filters.Episode {
+		p.logger.Info().Msgf("skipping %s no season or episode matched", item.Title)
+		return "episode"
+	}
+
+	for _, quality := range profile.ExcludedQualities {
+		if strings.EqualFold(item.Quality, quality) {
+			p.logger.Info().Msgf("skipping %s found excluded quality %s", item.Title, item.Quality)
+			return "quality"
 		}
-		return sizeI < sizeJ
-	})
+	}
 
-	// sort by seeds
-	sort.Slice(filtered, func(i, j int) bool {
-		seedsI := filtered[i].Seeds
-		seedsJ := filtered[j].Seeds
-		return seedsI > seedsJ
-	})
+	if len(profile.RequiredCodecs) > 0 && !matchesAny(item.Codec, profile.RequiredCodecs, normalizeCodec) {
+		p.logger.Info().Msgf("skipping %s codec %s is not required", item.Title, item.Codec)
+		return "codec"
+	}
 
-	p.logger.Info().Msgf("Total filtered: %d", len(filtered))
-	return filtered
+	if matchesAny(item.Codec, profile.ForbiddenCodecs, normalizeCodec) {
+		p.logger.Info().Msgf("skipping %s found forbidden codec %s", item.Title, item.Codec)
+		return "codec"
+	}
+
+	if item.Seeds < profile.MinSeeds {
+		p.logger.Info().Msgf("skipping %s not enough seeds %d", item.Title, item.Seeds)
+		return "seeds"
+	}
+
+	if len(profile.AllowedGroups) > 0 && !matchesAny(item.Group, profile.AllowedGroups, normalizeGroup) {
+		p.logger.Info().Msgf("skipping %s group %s is not allowed", item.Title, item.Group)
+		return "allowed_group"
+	}
+
+	if sizeRange, ok := profile.sizeRange(item.Type, item.Resolution); ok {
+		if item.SizeBytes <= 0 {
+			p.logger.Info().Msgf("skipping %s unknown size %s", item.Title, item.Size)
+			return "size"
+		}
+		if item.SizeBytes < sizeRange.min || (sizeRange.max > 0 && item.SizeBytes > sizeRange.max) {
+			p.logger.Info().Msgf("skipping %s no size matched", item.Size)
+			return "size"
+		}
+	}
+
+	return ""
 }
@@ -0,0 +1,230 @@
+package providers
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+)
+
+// ValidationError points at a single problem of a provider config, Field is
+// the json path of the offending key.
+type ValidationError struct {
+	File    string `json:"file"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.File, e.Field, e.Message)
+}
+
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	var problems []string
+	for _, problem := range e {
+		problems = append(problems, problem.Error())
+	}
+	return fmt.Sprintf("invalid provider config, %d problem(s): %s", len(e), strings.Join(problems, "; "))
+}
+
+func asValidationErrors(source string, err error) ValidationErrors {
+	if problems, ok := err.(ValidationErrors); ok {
+		return problems
+	}
+	return ValidationErrors{{File: source, Message: err.Error()}}
+}
+
+// ValidateProviders reads and validates every config as it is on disk, an
+// empty result means the configs can be loaded.
+func (p *TorrentManager) ValidateProviders() ValidationErrors {
+	_, err := p.loadProviderSet()
+	if err == nil {
+		return nil
+	}
+	return asValidationErrors("", err)
+}
+
+// unknownFields reports every key of data that has no matching json field in t.
+func unknownFields(source string, prefix string, data interface{}, t reflect.Type) ValidationErrors {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var problems ValidationErrors
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := data.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			fields[name] = field.Type
+		}
+		for _, key := range sortedKeys(object) {
+			value := object[key]
+			fieldType, ok := fields[key]
+			if !ok {
+				problems = append(problems, &ValidationError{File: source, Field: joinField(prefix, key), Message: "unknown field"})
+				continue
+			}
+			problems = append(problems, unknownFields(source, joinField(prefix, key), value, fieldType)...)
+		}
+	case reflect.Map:
+		object, ok := data.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, key := range sortedKeys(object) {
+			problems = append(problems, unknownFields(source, joinField(prefix, key), object[key], t.Elem())...)
+		}
+	case reflect.Slice:
+		list, ok := data.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, value := range list {
+			problems = append(problems, unknownFields(source, fmt.Sprintf("%s[%d]", prefix, i), value, t.Elem())...)
+		}
+	}
+	return problems
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	var keys []string
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinField(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func validateProviderConfig(source string, config *ProviderConfig) ValidationErrors {
+	var problems ValidationErrors
+	problem := func(field string, format string, args ...interface{}) {
+		problems = append(problems, &ValidationError{File: source, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	required := func(field string, value string) bool {
+		if strings.TrimSpace(value) == "" {
+			problem(field, "is required")
+			return false
+		}
+		return true
+	}
+	selector := func(field string, value string) {
+		if value == "" {
+			return
+		}
+		if _, err := cascadia.Compile(value); err != nil {
+			problem(field, "invalid css selector %q: %v", value, err)
+		}
+	}
+	baseUrl := func(field string, value string) {
+		if !required(field, value) {
+			return
+		}
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problem(field, "%q is not an absolute http(s) url", value)
+		}
+	}
+
+	required("name", config.Name)
+	if required("type", config.Type) && !IsRegistered(config.Type) {
+		problem("type", "unknown type %q", config.Type)
+	}
+	baseUrl("url", config.BaseUrl)
+	for i, mirror := range config.Mirrors {
+		baseUrl(fmt.Sprintf("mirrors[%d]", i), mirror)
+	}
+	if required("searchUrl", config.SearchUrl) && !strings.Contains(config.SearchUrl, "{query}") {
+		problem("searchUrl", "missing the {query} placeholder")
+	}
+	if config.Timeout != "" {
+		if timeout, err := time.ParseDuration(config.Timeout); err != nil {
+			problem("timeout", "invalid duration %q", config.Timeout)
+		} else if timeout <= 0 {
+			problem("timeout", "must be positive, got %q", config.Timeout)
+		}
+	}
+	switch config.MirrorPolicy {
+	case "", MirrorPolicyFailover, MirrorPolicyFastest:
+	default:
+		problem("mirrorPolicy", "unknown mirror policy %q, expected %s or %s", config.MirrorPolicy, MirrorPolicyFailover, MirrorPolicyFastest)
+	}
+	if config.Pagination != nil {
+		if config.Pagination.PageUrl != "" && !strings.Contains(config.Pagination.PageUrl, "{page}") {
+			problem("pagination.pageUrl", "missing the {page} placeholder")
+		}
+		if config.Pagination.MaxPages < 0 {
+			problem("pagination.maxPages", "must not be negative")
+		}
+		selector("pagination.nextPageSelector", config.Pagination.NextPageSelector)
+	}
+	if config.Proxy != nil {
+		for i, proxy := range config.Proxy.Urls {
+			if _, err := parseProxyUrl(proxy); err != nil {
+				problem(fmt.Sprintf("proxy.urls[%d]", i), "%v", err)
+			}
+		}
+	}
+	if config.Breaker != nil && config.Breaker.Cooldown != "" {
+		if _, err := time.ParseDuration(config.Breaker.Cooldown); err != nil {
+			problem("breaker.cooldown", "invalid duration %q", config.Breaker.Cooldown)
+		}
+	}
+
+	switch config.Type {
+	case "html":
+		if required("itemSelector", config.ItemSelector) {
+			selector("itemSelector", config.ItemSelector)
+		}
+		if required("itemsSelector.detail_url", config.ItemsSelector.DetailUrl) {
+			selector("itemsSelector.detail_url", config.ItemsSelector.DetailUrl)
+		}
+		if required("itemsSelector.title", config.ItemsSelector.Title) {
+			selector("itemsSelector.title", config.ItemsSelector.Title)
+		}
+		if required("itemsSelector.magnetSelector", config.ItemsSelector.MagnetSelector) {
+			selector("itemsSelector.magnetSelector", config.ItemsSelector.MagnetSelector)
+		}
+		selector("itemsSelector.seeds", config.ItemsSelector.Seeds)
+		selector("itemsSelector.peers", config.ItemsSelector.Peers)
+		selector("itemsSelector.size", config.ItemsSelector.Size)
+	case "api":
+		if config.Api == nil {
+			problem("api", "is required for api providers")
+			break
+		}
+		if config.Api.Fields.Name == "" && config.Api.Fields.Title == "" {
+			problem("api.fields", "name or title is required")
+		}
+		if config.Api.Fields.InfoHash == "" && config.Api.Fields.Magnet == "" {
+			problem("api.fields", "infoHash or magnet is required")
+		}
+	}
+	return problems
+}
@@ -0,0 +1,53 @@
+package providers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateProvidersReportsEveryProblem(t *testing.T) {
+	manager := newTestManager(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"yts.json":              `{"timeout": "-5s"}`,
+		"broken.json":           `{"name": `,
+		"dup.json":              `{}`,
+		"dup.yaml":              `name: dup`,
+		"filters/profiles.yaml": `default: missing`,
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manager.config.ProvidersDir = dir
+
+	problems := manager.ValidateProviders()
+	expected := []struct {
+		file    string
+		field   string
+		message string
+	}{
+		{file: filepath.Join(dir, "broken.json"), message: "unexpected end of JSON input"},
+		{file: filepath.Join(dir, "dup.yaml"), message: "also defined by " + filepath.Join(dir, "dup.json")},
+		{file: "embedded/yts.json + " + filepath.Join(dir, "yts.json"), field: "timeout", message: "must be positive"},
+		{file: filepath.Join(dir, "filters/profiles.yaml"), message: `default profile "missing" not found`},
+	}
+	for _, want := range expected {
+		found := false
+		for _, problem := range problems {
+			if problem.File == want.file && problem.Field == want.field && strings.Contains(problem.Message, want.message) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected %s %s: %s among the problems, got %v", want.file, want.field, want.message, problems)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package watchlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xochilpili/torrent-api-go/internal/persist"
+)
+
+const maxHistory = 1000
+
+var ErrNotFound = errors.New("watchlist item not found")
+
+// Item is a followed show, Season and Episode are the next expected episode.
+type Item struct {
+	Id            string     `json:"id"`
+	Title         string     `json:"title"`
+	Season        int        `json:"season"`
+	Episode       int        `json:"episode"`
+	Resolution    string     `json:"resolution,omitempty"`
+	Group         string     `json:"group,omitempty"`
+	Profile       string     `json:"profile,omitempty"`
+	Client        string     `json:"client,omitempty"`
+	Category      string     `json:"category,omitempty"`
+	SavePath      string     `json:"save_path,omitempty"`
+	Paused        bool       `json:"paused"`
+	Enabled       bool       `json:"enabled"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+// Grab records a torrent handed to a download client and why it was picked.
+type Grab struct {
+	ItemId    string    `json:"item_id"`
+	Title     string    `json:"title"`
+	Season    int       `json:"season"`
+	Episode   int       `json:"episode"`
+	Name      string    `json:"name"`
+	InfoHash  string    `json:"infohash"`
+	Magnet    string    `json:"magnet"`
+	Provider  string    `json:"provider"`
+	Score     float64   `json:"score"`
+	Client    string    `json:"client"`
+	Reason    string    `json:"reason"`
+	GrabbedAt time.Time `json:"grabbed_at"`
+}
+
+type storeData struct {
+	Items   []*Item `json:"items"`
+	History []*Grab `json:"history"`
+}
+
+// Store keeps the watchlist in a json file, every change is written right away.
+type Store struct {
+	mu   sync.RWMutex
+	file string
+	data storeData
+}
+
+func NewStore(file string) (*Store, error) {
+	s := &Store{file: file}
+	raw, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &s.data); err != nil {
+		return nil, fmt.Errorf("watchlist %s: %w", file, err)
+	}
+	return s, nil
+}
+
+func (s *Store) save() error {
+	raw, err := json.MarshalIndent(&s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return persist.WriteFile(s.file, raw)
+}
+
+func (s *Store) Items() []*Item {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	items := make([]*Item, 0, len(s.data.Items))
+	for _, item := range s.data.Items {
+		copied := *item
+		items = append(items, &copied)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+	return items
+}
+
+func (s *Store) Get(id string) (*Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, item := range s.data.Items {
+		if item.Id == id {
+			copied := *item
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+func (s *Store) Add(item *Item) (*Item, error) {
+	id, err := persist.NewId()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *item
+	copied.Id = id
+	copied.CreatedAt = time.Now()
+	copied.UpdatedAt = copied.CreatedAt
+	s.data.Items = append(s.data.Items, &copied)
+	if err := s.save(); err != nil {
+		s.data.Items = s.data.Items[:len(s.data.Items)-1]
+		return nil, err
+	}
+	result := copied
+	return &result, nil
+}
+
+// Update applies change to the stored item and saves it.
+func (s *Store) Update(id string, change func(item *Item)) (*Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, item := range s.data.Items {
+		if item.Id != id {
+			continue
+		}
+		updated := *item
+		change(&updated)
+		updated.Id = item.Id
+		updated.CreatedAt = item.CreatedAt
+		updated.UpdatedAt = time.Now()
+		s.data.Items[i] = &updated
+		if err := s.save(); err != nil {
+			s.data.Items[i] = item
+			return nil, err
+		}
+		result := updated
+		return &result, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, item := range s.data.Items {
+		if item.Id != id {
+			continue
+		}
+		previous := s.data.Items
+		s.data.Items = append(append([]*Item{}, previous[:i]...), previous[i+1:]...)
+		if err := s.save(); err != nil {
+			s.data.Items = previous
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// RecordGrab adds a grab to the history and advances the item past the
+// grabbed episode in a single write.
+func (s *Store) RecordGrab(grab *Grab, season int, episode int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previousItems, previousHistory := s.data.Items, s.data.History
+	s.data.Items = append([]*Item{}, previousItems...)
+	for i, item := range s.data.Items {
+		if item.Id == grab.ItemId {
+			updated := *item
+			updated.Season = season
+			updated.Episode = episode
+			updated.UpdatedAt = time.Now()
+			s.data.Items[i] = &updated
+		}
+	}
+	s.data.History = append(append([]*Grab{}, previousHistory...), grab)
+	if len(s.data.History) > maxHistory {
+		s.data.History = s.data.History[len(s.data.History)-maxHistory:]
+	}
+	if err := s.save(); err != nil {
+		s.data.Items, s.data.History = previousItems, previousHistory
+		return err
+	}
+	return nil
+}
+
+// History returns the grabs of an item, or of every item for an empty id,
+// newest first.
+func (s *Store) History(id string) []*Grab {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	history := []*Grab{}
+	for i := len(s.data.History) - 1; i >= 0; i-- {
+		if id == "" || s.data.History[i].ItemId == id {
+			history = append(history, s.data.History[i])
+		}
+	}
+	return history
+}
+
+func (s *Store) Grabbed(infoHash string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, grab := range s.data.History {
+		if grab.InfoHash != "" && grab.InfoHash == infoHash {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package watchlist
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/torrent-api-go/internal/downloader"
+	"github.com/xochilpili/torrent-api-go/internal/providers"
+)
+
+type Searcher interface {
+	FetchAllActive(ctx context.Context, params providers.SearchParams) (*providers.SearchResult, error)
+}
+
+type Grabber interface {
+	Add(ctx context.Context, name string, request *downloader.Request) (string, error)
+}
+
+// Watchlist follows the items of the store, each check searches every active
+// provider for the next expected episode and grabs the best scored match.
+type Watchlist struct {
+	store    *Store
+	searcher Searcher
+	grabber  Grabber
+	logger   *zerolog.Logger
+	checking sync.Mutex
+}
+
+func New(store *Store, searcher Searcher, grabber Grabber, logger *zerolog.Logger) *Watchlist {
+	return &Watchlist{
+		store:    store,
+		searcher: searcher,
+		grabber:  grabber,
+		logger:   logger,
+	}
+}
+
+func (w *Watchlist) Store() *Store {
+	return w.store
+}
+
+// Watch checks every enabled item on each interval, it returns once ctx is
+// done.
+func (w *Watchlist) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		w.CheckAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Watchlist) CheckAll(ctx context.Context) {
+	for _, item := range w.store.Items() {
+		if !item.Enabled {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := w.Check(ctx, item.Id); err != nil {
+			w.logger.Err(err).Msgf("error while checking watchlist item %s: %v", item.Title, err)
+		}
+	}
+}
+
+// Check looks for the next episode of the item, falling back to the first
+// episode of the next season. It returns the grab or nil when nothing matched.
+func (w *Watchlist) Check(ctx context.Context, id string) (*Grab, error) {
+	w.checking.Lock()
+	defer w.checking.Unlock()
+
+	item, err := w.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	grab, err := w.grabEpisode(ctx, item, item.Season, item.Episode)
+	if err == nil && grab == nil {
+		grab, err = w.grabEpisode(ctx, item, item.Season+1, 1)
+	}
+
+	checked := time.Now()
+	_, updateErr := w.store.Update(id, func(stored *Item) {
+		stored.LastCheckedAt = &checked
+		stored.LastError = ""
+		if err != nil {
+			stored.LastError = err.Error()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return grab, updateErr
+}
+
+func (w *Watchlist) grabEpisode(ctx context.Context, item *Item, season int, episode int) (*Grab, error) {
+	query := fmt.Sprintf("%s S%02dE%02d", item.Title, season, episode)
+	result, err := w.searcher.FetchAllActive(ctx, providers.SearchParams{
+		Query: url.PathEscape(query),
+		Filters: providers.ParamFilters{
+			Title:      item.Title,
+			Resolution: strings.ToLower(item.Resolution),
+			Group:      strings.ToLower(item.Group),
+			Season:     season,
+			Episode:    episode,
+		},
+		Profile: item.Profile,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	torrent, matches := w.bestMatch(result.Torrents, season, episode)
+	if torrent == nil {
+		w.logger.Info().Msgf("watchlist: no match for %s out of %d results", query, len(result.Torrents))
+		return nil, nil
+	}
+
+	client, err := w.grabber.Add(ctx, item.Client, &downloader.Request{
+		Magnet:   torrent.Magnet,
+		Category: item.Category,
+		SavePath: item.SavePath,
+		Paused:   item.Paused,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	grab := &Grab{
+		ItemId:    item.Id,
+		Title:     item.Title,
+		Season:    season,
+		Episode:   episode,
+		Name:      torrent.OriginalTitle,
+		InfoHash:  torrent.InfoHash,
+		Magnet:    torrent.Magnet,
+		Provider:  torrent.Provider,
+		Score:     torrent.Score,
+		Client:    client,
+		Reason:    grabReason(torrent, matches, len(result.Torrents)),
+		GrabbedAt: time.Now(),
+	}
+	if err := w.store.RecordGrab(grab, season, episode+1); err != nil {
+		return nil, err
+	}
+	w.logger.Info().Msgf("watchlist: grabbed %s from %s into %s", query, torrent.Provider, client)
+	return grab, nil
+}
+
+// bestMatch returns the highest scored torrent of the exact episode that was
+// not grabbed before, together with the number of candidates.
+func (w *Watchlist) bestMatch(torrents []*providers.Torrent, season int, episode int) (*providers.Torrent, int) {
+	var best *providers.Torrent
+	matches := 0
+	for _, torrent := range torrents {
+		if torrent.Season != season || torrent.Episode != episode || torrent.Magnet == "" {
+			continue
+		}
+		if w.store.Grabbed(torrent.InfoHash) {
+			continue
+		}
+		matches++
+		if best == nil || torrent.Score > best.Score {
+			best = torrent
+		}
+	}
+	return best, matches
+}
+
+func grabReason(torrent *providers.Torrent, matches int, total int) string {
+	details := []string{fmt.Sprintf("%d seeds", torrent.Seeds)}
+	if torrent.Resolution != "" {
+		details = append(details, torrent.Resolution)
+	}
+	if torrent.Group != "" {
+		details = append(details, "group "+torrent.Group)
+	}
+	return fmt.Sprintf("best score %.2f of %d matching out of %d results (%s)", torrent.Score, matches, total, strings.Join(details, ", "))
+}
@@ -0,0 +1,158 @@
+package watchlist
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/torrent-api-go/internal/downloader"
+	"github.com/xochilpili/torrent-api-go/internal/providers"
+)
+
+// fakeSearcher answers the torrents of the searched season and episode.
+type fakeSearcher struct {
+	torrents []*providers.Torrent
+	searches []providers.SearchParams
+}
+
+func (f *fakeSearcher) FetchAllActive(ctx context.Context, params providers.SearchParams) (*providers.SearchResult, error) {
+	f.searches = append(f.searches, params)
+	var torrents []*providers.Torrent
+	for _, torrent := range f.torrents {
+		if torrent.Season == params.Filters.Season && torrent.Episode == params.Filters.Episode {
+			torrents = append(torrents, torrent)
+		}
+	}
+	return &providers.SearchResult{Torrents: torrents}, nil
+}
+
+type fakeGrabber struct {
+	requests []*downloader.Request
+}
+
+func (f *fakeGrabber) Add(ctx context.Context, name string, request *downloader.Request) (string, error) {
+	f.requests = append(f.requests, request)
+	return "qbittorrent", nil
+}
+
+func newTestWatchlist(t *testing.T, torrents ...*providers.Torrent) (*Watchlist, *fakeSearcher, *fakeGrabber) {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "watchlist.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zerolog.Nop()
+	searcher := &fakeSearcher{torrents: torrents}
+	grabber := &fakeGrabber{}
+	return New(store, searcher, grabber, &logger), searcher, grabber
+}
+
+func episode(season int, number int, hash string, score float64) *providers.Torrent {
+	return &providers.Torrent{
+		Provider:      "test",
+		OriginalTitle: hash,
+		Season:        season,
+		Episode:       number,
+		InfoHash:      hash,
+		Magnet:        "magnet:?xt=urn:btih:" + hash,
+		Score:         score,
+	}
+}
+
+func TestBestMatch(t *testing.T) {
+	w, _, _ := newTestWatchlist(t)
+	if err := w.store.RecordGrab(&Grab{InfoHash: "grabbed"}, 1, 3); err != nil {
+		t.Fatal(err)
+	}
+	unlinked := episode(1, 2, "unlinked", 9)
+	unlinked.Magnet = ""
+
+	best, matches := w.bestMatch([]*providers.Torrent{
+		episode(1, 2, "low", 1),
+		episode(1, 2, "high", 5),
+		episode(1, 2, "grabbed", 8),
+		episode(1, 3, "next", 7),
+		episode(2, 2, "other-season", 7),
+		unlinked,
+	}, 1, 2)
+	if best == nil || best.InfoHash != "high" {
+		t.Fatalf("expected the highest scored torrent not grabbed before, got %+v", best)
+	}
+	if matches != 2 {
+		t.Fatalf("expected 2 candidates, got %d", matches)
+	}
+
+	if best, _ := w.bestMatch([]*providers.Torrent{episode(1, 3, "next", 7)}, 1, 2); best != nil {
+		t.Fatalf("expected no match for another episode, got %+v", best)
+	}
+}
+
+func TestCheckAdvancesEpisode(t *testing.T) {
+	w, _, grabber := newTestWatchlist(t, episode(1, 4, "s01e04", 3))
+	item, err := w.store.Add(&Item{Title: "show", Season: 1, Episode: 4, Category: "tv", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grab, err := w.Check(context.Background(), item.Id)
+	if err != nil {
+		t.Fatalf("error while checking: %v", err)
+	}
+	if grab == nil || grab.Season != 1 || grab.Episode != 4 || grab.InfoHash != "s01e04" {
+		t.Fatalf("expected S01E04 to be grabbed, got %+v", grab)
+	}
+	if len(grabber.requests) != 1 || grabber.requests[0].Category != "tv" {
+		t.Fatalf("expected the magnet to be sent to the client, got %+v", grabber.requests)
+	}
+	stored, _ := w.store.Get(item.Id)
+	if stored.Season != 1 || stored.Episode != 5 {
+		t.Fatalf("expected the item to wait for S01E05, got S%02dE%02d", stored.Season, stored.Episode)
+	}
+	if history := w.store.History(item.Id); len(history) != 1 {
+		t.Fatalf("expected the grab in the history, got %d", len(history))
+	}
+}
+
+func TestCheckFallsBackToNextSeason(t *testing.T) {
+	w, searcher, _ := newTestWatchlist(t, episode(3, 1, "s03e01", 2))
+	item, err := w.store.Add(&Item{Title: "show", Season: 2, Episode: 11, Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grab, err := w.Check(context.Background(), item.Id)
+	if err != nil {
+		t.Fatalf("error while checking: %v", err)
+	}
+	if grab == nil || grab.Season != 3 || grab.Episode != 1 {
+		t.Fatalf("expected S03E01 to be grabbed, got %+v", grab)
+	}
+	if len(searcher.searches) != 2 || searcher.searches[0].Filters.Season != 2 || searcher.searches[0].Filters.Episode != 11 {
+		t.Fatalf("expected S02E11 to be searched first, got %+v", searcher.searches)
+	}
+	stored, _ := w.store.Get(item.Id)
+	if stored.Season != 3 || stored.Episode != 2 || stored.LastCheckedAt == nil {
+		t.Fatalf("expected the item to wait for S03E02, got S%02dE%02d", stored.Season, stored.Episode)
+	}
+}
+
+func TestCheckWithoutMatch(t *testing.T) {
+	w, _, grabber := newTestWatchlist(t, episode(1, 1, "s01e01", 2))
+	item, err := w.store.Add(&Item{Title: "show", Season: 1, Episode: 2, Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grab, err := w.Check(context.Background(), item.Id)
+	if err != nil || grab != nil {
+		t.Fatalf("expected no grab, got %+v, %v", grab, err)
+	}
+	if len(grabber.requests) != 0 {
+		t.Fatalf("expected nothing sent to the client, got %d", len(grabber.requests))
+	}
+	stored, _ := w.store.Get(item.Id)
+	if stored.Season != 1 || stored.Episode != 2 {
+		t.Fatalf("expected the item to keep waiting for S01E02, got S%02dE%02d", stored.Season, stored.Episode)
+	}
+}
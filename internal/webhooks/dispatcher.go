@@ -0,0 +1,282 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/torrent-api-go/internal/providers"
+)
+
+const (
+	EventRelease = "release"
+	EventTest    = "test"
+
+	queueSize       = 1000
+	targetQueueSize = 100
+	maxBackoff      = 5 * time.Minute
+
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+)
+
+// DefaultTemplate sends the whole torrent, the json function encodes any value
+// so templates stay valid json whatever the release name holds.
+const DefaultTemplate = `{"event":{{json .Event}},"search":{{json .Search}},"torrent":{{json .Torrent}},"sent_at":{{json .SentAt}}}`
+
+var ErrInvalidTemplate = errors.New("invalid webhook template")
+
+// Payload is what templates render.
+type Payload struct {
+	Event   string             `json:"event"`
+	Search  *SavedSearch       `json:"search"`
+	Torrent *providers.Torrent `json:"torrent"`
+	SentAt  time.Time          `json:"sent_at"`
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		raw, err := json.Marshal(value)
+		return string(raw), err
+	},
+}
+
+func parseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+	tmpl, err := template.New("webhook").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// Render executes the target template and checks the result is json.
+func Render(text string, payload *Payload) ([]byte, error) {
+	tmpl, err := parseTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	if !json.Valid(body.Bytes()) {
+		return nil, fmt.Errorf("%w: rendered payload is not valid json", ErrInvalidTemplate)
+	}
+	return body.Bytes(), nil
+}
+
+// SamplePayload is used to validate and test targets.
+func SamplePayload() *Payload {
+	return &Payload{
+		Event:  EventTest,
+		Search: &SavedSearch{Id: "sample", Name: "sample", Term: "big buck bunny"},
+		Torrent: &providers.Torrent{
+			Provider:      "sample",
+			Type:          "movie",
+			Title:         "Big Buck Bunny",
+			OriginalTitle: "Big.Buck.Bunny.2008.1080p.BluRay.x264-GROUP",
+			Year:          2008,
+			Group:         "GROUP",
+			Resolution:    "1080p",
+			Quality:       "BluRay",
+			Seeds:         42,
+			Peers:         7,
+			Size:          "1.2 GB",
+			SizeBytes:     1288490188,
+			Magnet:        "magnet:?xt=urn:btih:dd8255ecdc7ca55fb0bbf81323d87062db1f6d1c",
+			InfoHash:      "dd8255ecdc7ca55fb0bbf81323d87062db1f6d1c",
+		},
+		SentAt: time.Now(),
+	}
+}
+
+// Sign returns the hex encoded hmac sha256 of timestamp.body, receivers
+// recompute it with the shared secret.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type job struct {
+	target  *Target
+	payload *Payload
+}
+
+// Dispatcher delivers payloads from a queue, failed attempts are retried with
+// an exponential backoff and every outcome lands in the delivery log. Each
+// target has its own worker so a target that is down only delays itself.
+type Dispatcher struct {
+	store   *Store
+	logger  *zerolog.Logger
+	client  *http.Client
+	retries int
+	backoff time.Duration
+	queue   chan *job
+}
+
+func NewDispatcher(store *Store, logger *zerolog.Logger, timeout time.Duration, retries int, backoff time.Duration) *Dispatcher {
+	return &Dispatcher{
+		store:   store,
+		logger:  logger,
+		client:  &http.Client{Timeout: timeout},
+		retries: retries,
+		backoff: backoff,
+		queue:   make(chan *job, queueSize),
+	}
+}
+
+// Serve hands queued payloads to the worker of their target, it returns once
+// ctx is done and every worker stopped.
+func (d *Dispatcher) Serve(ctx context.Context) {
+	var workers sync.WaitGroup
+	defer workers.Wait()
+	queues := make(map[string]chan *job)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case next := <-d.queue:
+			queue, ok := queues[next.target.Id]
+			if !ok {
+				queue = make(chan *job, targetQueueSize)
+				queues[next.target.Id] = queue
+				workers.Add(1)
+				go func() {
+					defer workers.Done()
+					d.work(ctx, queue)
+				}()
+			}
+			select {
+			case queue <- next:
+			default:
+				d.record(next.target, next.payload, &Delivery{Status: DeliveryFailed, Error: "delivery queue is full"})
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) work(ctx context.Context, queue <-chan *job) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-queue:
+			// select picks at random once ctx is done too
+			if ctx.Err() != nil {
+				return
+			}
+			d.deliver(ctx, job.target, job.payload)
+		}
+	}
+}
+
+func (d *Dispatcher) Enqueue(target *Target, payload *Payload) {
+	select {
+	case d.queue <- &job{target: target, payload: payload}:
+	default:
+		d.record(target, payload, &Delivery{Status: DeliveryFailed, Error: "delivery queue is full"})
+	}
+}
+
+// Deliver sends the payload right away, retrying like queued deliveries.
+func (d *Dispatcher) Deliver(ctx context.Context, target *Target, payload *Payload) *Delivery {
+	return d.deliver(ctx, target, payload)
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, target *Target, payload *Payload) *Delivery {
+	delivery := &Delivery{Status: DeliveryFailed}
+	body, err := Render(target.Template, payload)
+	if err != nil {
+		delivery.Error = err.Error()
+		return d.record(target, payload, delivery)
+	}
+
+	wait := d.backoff
+	for attempt := 1; attempt <= d.retries+1; attempt++ {
+		delivery.Attempts = attempt
+		code, retry, err := d.send(ctx, target, payload.Event, body)
+		delivery.StatusCode = code
+		if err == nil {
+			delivery.Status = DeliveryDelivered
+			delivery.Error = ""
+			break
+		}
+		delivery.Error = err.Error()
+		if !retry || attempt > d.retries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			delivery.Error = ctx.Err().Error()
+			return d.record(target, payload, delivery)
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, maxBackoff)
+	}
+	return d.record(target, payload, delivery)
+}
+
+// send posts the body once, client errors other than 429 are not retried.
+func (d *Dispatcher) send(ctx context.Context, target *Target, event string, body []byte) (int, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "torrent-api-webhooks")
+	for key, value := range target.Headers {
+		req.Header.Set(key, value)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(EventHeader, event)
+	if target.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(target.Secret, timestamp, body))
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res.StatusCode, false, nil
+	}
+	retry := res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+	return res.StatusCode, retry, fmt.Errorf("target answered with status %d", res.StatusCode)
+}
+
+func (d *Dispatcher) record(target *Target, payload *Payload, delivery *Delivery) *Delivery {
+	delivery.TargetId = target.Id
+	delivery.CreatedAt = time.Now()
+	if payload.Event == EventRelease {
+		delivery.SearchId = payload.Search.Id
+		delivery.InfoHash = payload.Torrent.InfoHash
+		delivery.Name = payload.Torrent.OriginalTitle
+	}
+	if delivery.Status == DeliveryFailed {
+		d.logger.Warn().Msgf("webhook %s failed after %d attempts: %s", target.Name, delivery.Attempts, delivery.Error)
+	}
+	if err := d.store.AddDelivery(delivery); err != nil {
+		d.logger.Err(err).Msgf("error while saving webhook delivery: %v", err)
+	}
+	return delivery
+}
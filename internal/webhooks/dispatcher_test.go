@@ -0,0 +1,76 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestDeadTargetDoesNotBlockOthers(t *testing.T) {
+	var deadCalls int32
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&deadCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer dead.Close()
+	alive := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer alive.Close()
+
+	store, err := NewStore(filepath.Join(t.TempDir(), "webhooks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zerolog.Nop()
+	// the dead target waits a minute before its first retry
+	dispatcher := NewDispatcher(store, &logger, time.Second, 3, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		dispatcher.Serve(ctx)
+		close(served)
+	}()
+
+	deadTarget := &Target{Id: "dead", Name: "dead", Url: dead.URL, Enabled: true}
+	aliveTarget := &Target{Id: "alive", Name: "alive", Url: alive.URL, Enabled: true}
+	dispatcher.Enqueue(deadTarget, SamplePayload())
+	dispatcher.Enqueue(deadTarget, SamplePayload())
+	dispatcher.Enqueue(aliveTarget, SamplePayload())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(store.Deliveries("alive", "", DeliveryDelivered, 0)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the live target to be delivered while the dead one is retrying")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if calls := atomic.LoadInt32(&deadCalls); calls > 1 {
+		t.Fatalf("expected the dead target to wait for its retry, got %d calls", calls)
+	}
+
+	cancel()
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Serve to return once ctx is done")
+	}
+	if deliveries := store.Deliveries("dead", "", "", 0); len(deliveries) != 1 || deliveries[0].Status != DeliveryFailed {
+		t.Fatalf("expected the pending retry to be recorded as failed on shutdown, got %+v", deliveries)
+	}
+}
+
+func TestTargetRedacted(t *testing.T) {
+	target := &Target{Secret: "secret", Headers: map[string]string{"Authorization": "Bearer token"}}
+	redacted := target.Redacted()
+	if redacted.Secret != RedactedValue || redacted.Headers["Authorization"] != RedactedValue {
+		t.Fatalf("expected secret and headers to be redacted, got %+v", redacted)
+	}
+	if target.Secret != "secret" || target.Headers["Authorization"] != "Bearer token" {
+		t.Fatalf("expected the target to be left untouched, got %+v", target)
+	}
+}
@@ -0,0 +1,146 @@
+package webhooks
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	parsetorrentname "github.com/xochilpili/go-parse-torrent-name"
+	"github.com/xochilpili/torrent-api-go/internal/providers"
+)
+
+type Searcher interface {
+	FetchAllActive(ctx context.Context, params providers.SearchParams) (*providers.SearchResult, error)
+	FetchByProvider(ctx context.Context, provider string, params providers.SearchParams) (*providers.SearchResult, error)
+}
+
+// Monitor re-runs the saved searches and hands releases with an unseen info
+// hash to the dispatcher. The first run of a search only records what is
+// already out there.
+type Monitor struct {
+	store      *Store
+	searcher   Searcher
+	dispatcher *Dispatcher
+	logger     *zerolog.Logger
+	running    sync.Mutex
+}
+
+func NewMonitor(store *Store, searcher Searcher, dispatcher *Dispatcher, logger *zerolog.Logger) *Monitor {
+	return &Monitor{
+		store:      store,
+		searcher:   searcher,
+		dispatcher: dispatcher,
+		logger:     logger,
+	}
+}
+
+func (m *Monitor) Store() *Store {
+	return m.store
+}
+
+func (m *Monitor) Dispatcher() *Dispatcher {
+	return m.dispatcher
+}
+
+// Watch runs every enabled saved search on each interval, it returns once ctx
+// is done.
+func (m *Monitor) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.RunAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) RunAll(ctx context.Context) {
+	for _, search := range m.store.Searches() {
+		if !search.Enabled {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := m.Run(ctx, search.Id); err != nil {
+			m.logger.Err(err).Msgf("error while running saved search %s: %v", search.Name, err)
+		}
+	}
+}
+
+// Run searches once and returns the releases that were not seen before.
+func (m *Monitor) Run(ctx context.Context, id string) ([]*providers.Torrent, error) {
+	m.running.Lock()
+	defer m.running.Unlock()
+
+	search, err := m.store.Search(id)
+	if err != nil {
+		return nil, err
+	}
+	ran := time.Now()
+	result, err := m.fetch(ctx, search)
+	if err != nil {
+		return nil, m.store.recordError(id, err)
+	}
+
+	byHash := make(map[string]*providers.Torrent)
+	hashes := []string{}
+	for _, torrent := range result.Torrents {
+		hash := strings.ToLower(torrent.InfoHash)
+		if hash == "" || byHash[hash] != nil {
+			continue
+		}
+		byHash[hash] = torrent
+		hashes = append(hashes, hash)
+	}
+	unseen := m.store.Unseen(id, hashes)
+	if err := m.store.RecordRun(id, unseen, ran, nil); err != nil {
+		return nil, err
+	}
+
+	fresh := []*providers.Torrent{}
+	for _, hash := range unseen {
+		fresh = append(fresh, byHash[hash])
+	}
+	if search.LastRunAt == nil {
+		m.logger.Info().Msgf("saved search %s: baseline of %d releases", search.Name, len(fresh))
+		return fresh, nil
+	}
+
+	for _, torrent := range fresh {
+		for _, targetId := range search.Targets {
+			target, err := m.store.Target(targetId)
+			if err != nil || !target.Enabled {
+				continue
+			}
+			m.dispatcher.Enqueue(target, &Payload{Event: EventRelease, Search: search, Torrent: torrent, SentAt: time.Now()})
+		}
+	}
+	m.logger.Info().Msgf("saved search %s: %d new releases", search.Name, len(fresh))
+	return fresh, nil
+}
+
+func (m *Monitor) fetch(ctx context.Context, search *SavedSearch) (*providers.SearchResult, error) {
+	info, _ := parsetorrentname.Parse(search.Term)
+	params := providers.SearchParams{
+		Query: url.PathEscape(search.Term),
+		Filters: providers.ParamFilters{
+			Title:      info.Title,
+			Resolution: strings.ToLower(search.Resolution),
+			Group:      strings.ToLower(search.Group),
+			Season:     info.Season,
+			Episode:    info.Episode,
+		},
+		Profile: search.Profile,
+	}
+	if search.Provider != "" {
+		return m.searcher.FetchByProvider(ctx, search.Provider, params)
+	}
+	return m.searcher.FetchAllActive(ctx, params)
+}
@@ -0,0 +1,432 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/xochilpili/torrent-api-go/internal/persist"
+)
+
+const (
+	maxSeen       = 2000
+	maxDeliveries = 1000
+)
+
+var ErrNotFound = errors.New("webhook item not found")
+
+// Target is an url receiving a POST with the rendered Template for every new
+// release of the saved searches pointing at it.
+type Target struct {
+	Id        string            `json:"id"`
+	Name      string            `json:"name"`
+	Url       string            `json:"url"`
+	Secret    string            `json:"secret,omitempty"`
+	Template  string            `json:"template,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Enabled   bool              `json:"enabled"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// RedactedValue replaces the secret and header values in what is handed out,
+// headers usually carry tokens of the receiving service.
+const RedactedValue = "xxxxx"
+
+// Redacted returns a copy of the target safe to hand out.
+func (t *Target) Redacted() *Target {
+	copied := *t
+	if copied.Secret != "" {
+		copied.Secret = RedactedValue
+	}
+	if len(t.Headers) > 0 {
+		copied.Headers = make(map[string]string, len(t.Headers))
+		for key := range t.Headers {
+			copied.Headers[key] = RedactedValue
+		}
+	}
+	return &copied
+}
+
+// SavedSearch is re-run on every interval, releases whose info hash was not
+// seen by an earlier run are sent to its Targets.
+type SavedSearch struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Term       string     `json:"term"`
+	Provider   string     `json:"provider,omitempty"`
+	Resolution string     `json:"resolution,omitempty"`
+	Group      string     `json:"group,omitempty"`
+	Profile    string     `json:"profile,omitempty"`
+	Targets    []string   `json:"targets"`
+	Enabled    bool       `json:"enabled"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+	LastNew    int        `json:"last_new"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+const (
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery is the outcome of sending one release to one target.
+type Delivery struct {
+	TargetId   string    `json:"target_id"`
+	SearchId   string    `json:"search_id,omitempty"`
+	InfoHash   string    `json:"infohash,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Status     string    `json:"status"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type storeData struct {
+	Targets    []*Target           `json:"targets"`
+	Searches   []*SavedSearch      `json:"searches"`
+	Seen       map[string][]string `json:"seen"`
+	Deliveries []*Delivery         `json:"deliveries"`
+}
+
+// Store keeps targets, saved searches, the info hashes seen by each search
+// and the delivery log in a json file.
+type Store struct {
+	mu   sync.RWMutex
+	file string
+	data storeData
+}
+
+func NewStore(file string) (*Store, error) {
+	s := &Store{file: file}
+	raw, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(raw, &s.data); err != nil {
+			return nil, fmt.Errorf("webhooks %s: %w", file, err)
+		}
+	}
+	if s.data.Seen == nil {
+		s.data.Seen = make(map[string][]string)
+	}
+	return s, nil
+}
+
+// commit saves the data after change, the previous data is restored when the
+// file could not be written. Callers hold the lock.
+func (s *Store) commit(change func(data *storeData)) error {
+	previous := s.data
+	next := storeData{
+		Targets:    append([]*Target{}, previous.Targets...),
+		Searches:   append([]*SavedSearch{}, previous.Searches...),
+		Seen:       make(map[string][]string, len(previous.Seen)),
+		Deliveries: append([]*Delivery{}, previous.Deliveries...),
+	}
+	for id, hashes := range previous.Seen {
+		next.Seen[id] = hashes
+	}
+	change(&next)
+	s.data = next
+	if err := s.save(); err != nil {
+		s.data = previous
+		return err
+	}
+	return nil
+}
+
+func (s *Store) save() error {
+	raw, err := json.MarshalIndent(&s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return persist.WriteFile(s.file, raw)
+}
+
+func (s *Store) Targets() []*Target {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	targets := make([]*Target, 0, len(s.data.Targets))
+	for _, target := range s.data.Targets {
+		copied := *target
+		targets = append(targets, &copied)
+	}
+	return targets
+}
+
+func (s *Store) Target(id string) (*Target, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if i := indexOf(s.data.Targets, id, targetId); i >= 0 {
+		copied := *s.data.Targets[i]
+		return &copied, nil
+	}
+	return nil, fmt.Errorf("%w: target %s", ErrNotFound, id)
+}
+
+func (s *Store) AddTarget(target *Target) (*Target, error) {
+	id, err := persist.NewId()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := *target
+	added.Id = id
+	added.CreatedAt = time.Now()
+	added.UpdatedAt = added.CreatedAt
+	err = s.commit(func(data *storeData) {
+		data.Targets = append(data.Targets, &added)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := added
+	return &result, nil
+}
+
+func (s *Store) UpdateTarget(id string, change func(target *Target)) (*Target, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := indexOf(s.data.Targets, id, targetId)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: target %s", ErrNotFound, id)
+	}
+	updated := *s.data.Targets[i]
+	change(&updated)
+	updated.Id = id
+	updated.CreatedAt = s.data.Targets[i].CreatedAt
+	updated.UpdatedAt = time.Now()
+	err := s.commit(func(data *storeData) {
+		data.Targets[i] = &updated
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := updated
+	return &result, nil
+}
+
+// DeleteTarget removes the target and drops it from the saved searches.
+func (s *Store) DeleteTarget(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := indexOf(s.data.Targets, id, targetId)
+	if i < 0 {
+		return fmt.Errorf("%w: target %s", ErrNotFound, id)
+	}
+	return s.commit(func(data *storeData) {
+		data.Targets = append(data.Targets[:i], data.Targets[i+1:]...)
+		for j, search := range data.Searches {
+			targets := []string{}
+			for _, target := range search.Targets {
+				if target != id {
+					targets = append(targets, target)
+				}
+			}
+			if len(targets) != len(search.Targets) {
+				updated := *search
+				updated.Targets = targets
+				data.Searches[j] = &updated
+			}
+		}
+	})
+}
+
+func (s *Store) Searches() []*SavedSearch {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	searches := make([]*SavedSearch, 0, len(s.data.Searches))
+	for _, search := range s.data.Searches {
+		copied := *search
+		searches = append(searches, &copied)
+	}
+	return searches
+}
+
+func (s *Store) Search(id string) (*SavedSearch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if i := indexOf(s.data.Searches, id, searchId); i >= 0 {
+		copied := *s.data.Searches[i]
+		return &copied, nil
+	}
+	return nil, fmt.Errorf("%w: search %s", ErrNotFound, id)
+}
+
+func (s *Store) AddSearch(search *SavedSearch) (*SavedSearch, error) {
+	id, err := persist.NewId()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := *search
+	added.Id = id
+	added.CreatedAt = time.Now()
+	added.UpdatedAt = added.CreatedAt
+	err = s.commit(func(data *storeData) {
+		data.Searches = append(data.Searches, &added)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := added
+	return &result, nil
+}
+
+// UpdateSearch applies change to the saved search, changing what it looks
+// for starts over from a fresh baseline.
+func (s *Store) UpdateSearch(id string, change func(search *SavedSearch)) (*SavedSearch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := indexOf(s.data.Searches, id, searchId)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: search %s", ErrNotFound, id)
+	}
+	current := s.data.Searches[i]
+	updated := *current
+	change(&updated)
+	updated.Id = id
+	updated.CreatedAt = current.CreatedAt
+	updated.UpdatedAt = time.Now()
+	reset := updated.Term != current.Term || updated.Provider != current.Provider || updated.Resolution != current.Resolution ||
+		updated.Group != current.Group || updated.Profile != current.Profile
+	if reset {
+		updated.LastRunAt = nil
+		updated.LastNew = 0
+	}
+	err := s.commit(func(data *storeData) {
+		data.Searches[i] = &updated
+		if reset {
+			delete(data.Seen, id)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := updated
+	return &result, nil
+}
+
+func (s *Store) DeleteSearch(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := indexOf(s.data.Searches, id, searchId)
+	if i < 0 {
+		return fmt.Errorf("%w: search %s", ErrNotFound, id)
+	}
+	return s.commit(func(data *storeData) {
+		data.Searches = append(data.Searches[:i], data.Searches[i+1:]...)
+		delete(data.Seen, id)
+	})
+}
+
+// Unseen returns the hashes the saved search did not see before.
+func (s *Store) Unseen(id string, hashes []string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	seen := make(map[string]bool)
+	for _, hash := range s.data.Seen[id] {
+		seen[hash] = true
+	}
+	unseen := []string{}
+	for _, hash := range hashes {
+		if !seen[hash] {
+			seen[hash] = true
+			unseen = append(unseen, hash)
+		}
+	}
+	return unseen
+}
+
+// RecordRun remembers the new hashes of a run, only the latest maxSeen hashes
+// of a search are kept.
+func (s *Store) RecordRun(id string, hashes []string, ran time.Time, runErr error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := indexOf(s.data.Searches, id, searchId)
+	if i < 0 {
+		return fmt.Errorf("%w: search %s", ErrNotFound, id)
+	}
+	return s.commit(func(data *storeData) {
+		updated := *data.Searches[i]
+		updated.LastError = ""
+		if runErr != nil {
+			updated.LastError = runErr.Error()
+		} else {
+			updated.LastRunAt = &ran
+			updated.LastNew = len(hashes)
+		}
+		data.Searches[i] = &updated
+
+		seen := append(append([]string{}, data.Seen[id]...), hashes...)
+		if len(seen) > maxSeen {
+			seen = seen[len(seen)-maxSeen:]
+		}
+		data.Seen[id] = seen
+	})
+}
+
+// recordError keeps the failure of a run on the search and returns it.
+func (s *Store) recordError(id string, runErr error) error {
+	if err := s.RecordRun(id, nil, time.Time{}, runErr); err != nil {
+		return err
+	}
+	return runErr
+}
+
+func (s *Store) AddDelivery(delivery *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commit(func(data *storeData) {
+		data.Deliveries = append(data.Deliveries, delivery)
+		if len(data.Deliveries) > maxDeliveries {
+			data.Deliveries = data.Deliveries[len(data.Deliveries)-maxDeliveries:]
+		}
+	})
+}
+
+// Deliveries returns the delivery log newest first, empty filters match
+// every delivery.
+func (s *Store) Deliveries(target string, search string, status string, limit int) []*Delivery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	deliveries := []*Delivery{}
+	for i := len(s.data.Deliveries) - 1; i >= 0; i-- {
+		delivery := s.data.Deliveries[i]
+		if (target != "" && delivery.TargetId != target) || (search != "" && delivery.SearchId != search) || (status != "" && delivery.Status != status) {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+		if limit > 0 && len(deliveries) == limit {
+			break
+		}
+	}
+	return deliveries
+}
+
+func targetId(target *Target) string {
+	return target.Id
+}
+
+func searchId(search *SavedSearch) string {
+	return search.Id
+}
+
+func indexOf[T any](items []*T, id string, idOf func(*T) string) int {
+	for i, item := range items {
+		if idOf(item) == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,103 @@
+package webserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xochilpili/torrent-api-go/internal/providers"
+)
+
+// AdminAuth requires the TAG_ADMIN_TOKEN as a bearer token or in the
+// X-Admin-Token header, the admin api stays closed while no token is set.
+func (w *WebServer) AdminAuth(c *gin.Context) {
+	if w.config.AdminToken == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, &gin.H{"message": "error", "error": "admin api is disabled"})
+		return
+	}
+	token := c.GetHeader("X-Admin-Token")
+	if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		token = bearer
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(w.config.AdminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, &gin.H{"message": "error", "error": "unauthorized"})
+		return
+	}
+	c.Next()
+}
+
+func (w *WebServer) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": w.manager.AdminProviders()})
+}
+
+func (w *WebServer) GetProvider(c *gin.Context) {
+	provider, err := w.manager.AdminProvider(c.Param("provider"))
+	if err != nil {
+		c.JSON(adminErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": provider})
+}
+
+// UpdateProvider merges the json body into the provider config, only the
+// fields present in the body are overridden.
+func (w *WebServer) UpdateProvider(c *gin.Context) {
+	var patch map[string]interface{}
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	w.overrideProvider(c, patch)
+}
+
+func (w *WebServer) EnableProvider(c *gin.Context) {
+	w.overrideProvider(c, map[string]interface{}{"enabled": true})
+}
+
+func (w *WebServer) DisableProvider(c *gin.Context) {
+	w.overrideProvider(c, map[string]interface{}{"enabled": false})
+}
+
+func (w *WebServer) overrideProvider(c *gin.Context, patch map[string]interface{}) {
+	provider, err := w.manager.OverrideProvider(c.Param("provider"), patch)
+	if err != nil {
+		w.logger.Err(err).Msgf("error while updating provider %s: %v", c.Param("provider"), err)
+		c.JSON(adminErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": provider})
+}
+
+// ResetProvider drops every override made through the admin api.
+func (w *WebServer) ResetProvider(c *gin.Context) {
+	provider, err := w.manager.ResetProvider(c.Param("provider"))
+	if err != nil {
+		w.logger.Err(err).Msgf("error while resetting provider %s: %v", c.Param("provider"), err)
+		c.JSON(adminErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": provider})
+}
+
+// ValidateProviders checks every provider config as it is on disk right now.
+func (w *WebServer) ValidateProviders(c *gin.Context) {
+	problems := w.manager.ValidateProviders()
+	if len(problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, &gin.H{"message": "error", "valid": false, "errors": problems})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "valid": true, "errors": []interface{}{}})
+}
+
+func adminErrorStatus(err error) int {
+	var problems providers.ValidationErrors
+	if errors.As(err, &problems) {
+		return http.StatusUnprocessableEntity
+	}
+	if errors.Is(err, providers.ErrUnknownProvider) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,74 @@
+package webserver
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xochilpili/torrent-api-go/internal/downloader"
+	"github.com/xochilpili/torrent-api-go/internal/providers"
+)
+
+// downloadRequest takes either a magnet or the infohash of a result returned
+// by a previous search as id.
+type downloadRequest struct {
+	Magnet   string `json:"magnet"`
+	Id       string `json:"id"`
+	Client   string `json:"client"`
+	Category string `json:"category"`
+	SavePath string `json:"save_path"`
+	Paused   bool   `json:"paused"`
+}
+
+func (w *WebServer) Download(c *gin.Context) {
+	var body downloadRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+
+	var torrent *providers.Torrent
+	magnet := body.Magnet
+	if magnet == "" {
+		if body.Id == "" {
+			c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": "magnet or id is required"})
+			return
+		}
+		result, ok := w.manager.RecentResult(body.Id)
+		if !ok {
+			c.JSON(http.StatusNotFound, &gin.H{"message": "error", "error": "no recent search result with id " + body.Id})
+			return
+		}
+		torrent = result
+		magnet = result.Magnet
+	}
+	if !strings.HasPrefix(magnet, "magnet:?") {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": "invalid magnet link"})
+		return
+	}
+
+	client, err := w.downloader.Add(c.Request.Context(), body.Client, &downloader.Request{
+		Magnet:   magnet,
+		Category: body.Category,
+		SavePath: body.SavePath,
+		Paused:   body.Paused,
+	})
+	if err != nil {
+		w.logger.Err(err).Msgf("error while sending torrent to download client: %v", err)
+		c.JSON(downloadErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "client": client, "magnet": magnet, "data": torrent})
+}
+
+func (w *WebServer) ListDownloadClients(c *gin.Context) {
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": w.downloader.Clients()})
+}
+
+func downloadErrorStatus(err error) int {
+	if errors.Is(err, downloader.ErrUnknownClient) || errors.Is(err, downloader.ErrNoClient) {
+		return http.StatusBadRequest
+	}
+	return http.StatusBadGateway
+}
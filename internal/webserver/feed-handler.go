@@ -0,0 +1,284 @@
+package webserver
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xochilpili/torrent-api-go/internal/providers"
+)
+
+const (
+	feedFormatRss  = "rss"
+	feedFormatAtom = "atom"
+
+	magnetType = "application/x-bittorrent;x-scheme-handler/magnet"
+)
+
+// the torrent namespace is the one of ezrss, rss readers of download
+// clients such as flexget pick seeds and hashes from it
+type feedRss struct {
+	XMLName   xml.Name       `xml:"rss"`
+	Version   string         `xml:"version,attr"`
+	AtomNS    string         `xml:"xmlns:atom,attr"`
+	TorrentNS string         `xml:"xmlns:torrent,attr"`
+	Channel   feedRssChannel `xml:"channel"`
+}
+
+type feedRssChannel struct {
+	Title         string        `xml:"title"`
+	Description   string        `xml:"description"`
+	Link          string        `xml:"link"`
+	Self          feedAtomLink  `xml:"atom:link"`
+	LastBuildDate string        `xml:"lastBuildDate"`
+	Items         []feedRssItem `xml:"item"`
+}
+
+type feedRssItem struct {
+	Title       string           `xml:"title"`
+	Guid        feedRssGuid      `xml:"guid"`
+	Link        string           `xml:"link"`
+	Description string           `xml:"description"`
+	PubDate     string           `xml:"pubDate,omitempty"`
+	Categories  []string         `xml:"category"`
+	Enclosure   torznabEnclosure `xml:"enclosure"`
+	Torrent     feedRssTorrent   `xml:"torrent:torrent"`
+}
+
+type feedRssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type feedRssTorrent struct {
+	FileName      string `xml:"torrent:fileName"`
+	ContentLength int64  `xml:"torrent:contentLength,omitempty"`
+	InfoHash      string `xml:"torrent:infoHash,omitempty"`
+	MagnetUri     string `xml:"torrent:magnetURI"`
+	Seeds         int    `xml:"torrent:seeds"`
+	Peers         int    `xml:"torrent:peers"`
+}
+
+type feedAtom struct {
+	XMLName xml.Name        `xml:"http://www.w3.org/2005/Atom feed"`
+	Id      string          `xml:"id"`
+	Title   string          `xml:"title"`
+	Updated string          `xml:"updated"`
+	Link    feedAtomLink    `xml:"link"`
+	Entries []feedAtomEntry `xml:"entry"`
+}
+
+type feedAtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type feedAtomEntry struct {
+	Id         string             `xml:"id"`
+	Title      string             `xml:"title"`
+	Updated    string             `xml:"updated"`
+	Published  string             `xml:"published,omitempty"`
+	Summary    string             `xml:"summary"`
+	Links      []feedAtomLink     `xml:"link"`
+	Categories []feedAtomCategory `xml:"category"`
+}
+
+type feedAtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+func (w *WebServer) FeedSearchAll(c *gin.Context) {
+	w.feed(c, "")
+}
+
+func (w *WebServer) FeedSearchByProvider(c *gin.Context) {
+	provider := c.Param("provider")
+	if provider == "" {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": "bad request"})
+		return
+	}
+	w.feed(c, provider)
+}
+
+// feed runs the same search as SearchAll or SearchByProvider and renders it as
+// rss 2.0, or atom with format=atom.
+func (w *WebServer) feed(c *gin.Context, provider string) {
+	format := strings.ToLower(c.DefaultQuery("format", feedFormatRss))
+	if format != feedFormatRss && format != feedFormatAtom {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": "invalid format value, expected rss or atom"})
+		return
+	}
+	params, err := w.searchParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+
+	w.logger.Info().Msgf("feed searching %s to provider: %s with filters: %s", params.Query, provider, strings.Join([]string{params.Filters.Resolution, params.Filters.Group}, ","))
+	var result *providers.SearchResult
+	if provider == "" {
+		result, err = w.manager.FetchAllActive(c.Request.Context(), *params)
+	} else {
+		result, err = w.manager.FetchByProvider(c.Request.Context(), provider, *params)
+	}
+	if err != nil {
+		w.logger.Err(err).Msgf("error while fetching torrents: %v", err)
+		setRetryAfter(c, err)
+		c.JSON(searchErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+
+	title := "torrent-api: " + c.Query("term")
+	if provider != "" {
+		title = fmt.Sprintf("torrent-api (%s): %s", provider, c.Query("term"))
+	}
+	self := w.feedUrl(c)
+	published := w.publishedDates(c, result.Torrents)
+	w.logger.Info().Msgf("feed resolved %d torrents", len(result.Torrents))
+	if format == feedFormatAtom {
+		w.renderXMLAs(c, http.StatusOK, newAtomFeed(title, self, result.Torrents, published), "application/atom+xml; charset=utf-8")
+		return
+	}
+	w.renderXMLAs(c, http.StatusOK, newRssFeed(title, self, result.Torrents, published), "application/rss+xml; charset=utf-8")
+}
+
+// feedUrl is the self link of the feed, X-Forwarded-Proto is only honoured
+// from TAG_TRUSTED_PROXIES since any client can send it.
+func (w *WebServer) feedUrl(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := strings.ToLower(c.GetHeader("X-Forwarded-Proto")); (forwarded == "http" || forwarded == "https") && w.trustedProxy(c) {
+		scheme = forwarded
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, c.Request.Host, c.Request.URL.RequestURI())
+}
+
+// publishedDates are only known for torrents the archive saw before, providers
+// do not report upload dates so items have no pubDate unless
+// TAG_ARCHIVE_ENABLED is set.
+func (w *WebServer) publishedDates(c *gin.Context, torrents []*providers.Torrent) map[string]time.Time {
+	if w.archive == nil {
+		return nil
+	}
+	var hashes []string
+	for _, torrent := range torrents {
+		if torrent.InfoHash != "" {
+			hashes = append(hashes, torrent.InfoHash)
+		}
+	}
+	published, err := w.archive.FirstSeen(c.Request.Context(), hashes)
+	if err != nil {
+		w.logger.Err(err).Msgf("error while reading publish dates from the archive: %v", err)
+		return nil
+	}
+	return published
+}
+
+func feedGuid(torrent *providers.Torrent) string {
+	if torrent.InfoHash != "" {
+		return "urn:btih:" + strings.ToLower(torrent.InfoHash)
+	}
+	return torrent.Magnet
+}
+
+func feedSummary(torrent *providers.Torrent) string {
+	details := []string{fmt.Sprintf("Seeds: %d", torrent.Seeds), fmt.Sprintf("Peers: %d", torrent.Peers)}
+	if torrent.Size != "" {
+		details = append(details, "Size: "+torrent.Size)
+	}
+	if torrent.Resolution != "" {
+		details = append(details, "Resolution: "+torrent.Resolution)
+	}
+	if torrent.Group != "" {
+		details = append(details, "Group: "+torrent.Group)
+	}
+	details = append(details, "Provider: "+torrent.Provider)
+	return strings.Join(details, " | ")
+}
+
+func feedCategories(torrent *providers.Torrent) []string {
+	var categories []string
+	for _, category := range []string{torrent.Type, torrent.Resolution, torrent.Quality} {
+		if category != "" {
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
+
+func newRssFeed(title string, self string, torrents []*providers.Torrent, published map[string]time.Time) *feedRss {
+	feed := &feedRss{
+		Version:   "2.0",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		TorrentNS: "http://xmlns.ezrss.it/0.1/",
+		Channel: feedRssChannel{
+			Title:         title,
+			Description:   "torrent-api search feed",
+			Link:          self,
+			Self:          feedAtomLink{Href: self, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: time.Now().Format(time.RFC1123Z),
+			Items:         []feedRssItem{},
+		},
+	}
+	for _, torrent := range torrents {
+		item := feedRssItem{
+			Title:       torrent.OriginalTitle,
+			Guid:        feedRssGuid{Value: feedGuid(torrent)},
+			Link:        torrent.Magnet,
+			Description: feedSummary(torrent),
+			Categories:  feedCategories(torrent),
+			Enclosure:   torznabEnclosure{Url: torrent.Magnet, Length: torrent.SizeBytes, Type: magnetType},
+			Torrent: feedRssTorrent{
+				FileName:      torrent.OriginalTitle,
+				ContentLength: torrent.SizeBytes,
+				InfoHash:      torrent.InfoHash,
+				MagnetUri:     torrent.Magnet,
+				Seeds:         torrent.Seeds,
+				Peers:         torrent.Peers,
+			},
+		}
+		if date, ok := published[strings.ToLower(torrent.InfoHash)]; ok {
+			item.PubDate = date.Format(time.RFC1123Z)
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+	return feed
+}
+
+func newAtomFeed(title string, self string, torrents []*providers.Torrent, published map[string]time.Time) *feedAtom {
+	now := time.Now().Format(time.RFC3339)
+	feed := &feedAtom{
+		Id:      self,
+		Title:   title,
+		Updated: now,
+		Link:    feedAtomLink{Href: self, Rel: "self", Type: "application/atom+xml"},
+		Entries: []feedAtomEntry{},
+	}
+	for _, torrent := range torrents {
+		entry := feedAtomEntry{
+			Id:      feedGuid(torrent),
+			Title:   torrent.OriginalTitle,
+			Updated: now,
+			Summary: feedSummary(torrent),
+			Links: []feedAtomLink{
+				{Href: torrent.Magnet, Rel: "enclosure", Type: magnetType, Length: torrent.SizeBytes},
+			},
+		}
+		if date, ok := published[strings.ToLower(torrent.InfoHash)]; ok {
+			entry.Published = date.Format(time.RFC3339)
+			entry.Updated = entry.Published
+		}
+		for _, category := range feedCategories(torrent) {
+			entry.Categories = append(entry.Categories, feedAtomCategory{Term: category})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
@@ -0,0 +1,41 @@
+package webserver
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFeedUrlTrustsForwardedProtoFromProxiesOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	trustedProxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &WebServer{trustedProxies: trustedProxies}
+
+	tests := []struct {
+		remote   string
+		proto    string
+		expected string
+	}{
+		{remote: "10.1.2.3:4000", proto: "https", expected: "https://torrents.local/feed/search/all/?term=matrix"},
+		{remote: "192.168.1.5:4000", proto: "https", expected: "https://torrents.local/feed/search/all/?term=matrix"},
+		{remote: "192.168.1.6:4000", proto: "https", expected: "http://torrents.local/feed/search/all/?term=matrix"},
+		{remote: "10.1.2.3:4000", proto: "javascript", expected: "http://torrents.local/feed/search/all/?term=matrix"},
+	}
+	for _, test := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "http://torrents.local/feed/search/all/?term=matrix", nil)
+		c.Request.RemoteAddr = test.remote
+		c.Request.Header.Set("X-Forwarded-Proto", test.proto)
+		if got := w.feedUrl(c); got != test.expected {
+			t.Errorf("from %s with %s: expected %s, got %s", test.remote, test.proto, test.expected, got)
+		}
+	}
+
+	if _, err := parseTrustedProxies([]string{"proxy.local"}); err == nil {
+		t.Fatal("expected an error for a trusted proxy that is not an ip")
+	}
+}
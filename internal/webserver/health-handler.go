@@ -0,0 +1,33 @@
+package webserver
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (w *WebServer) ProvidersHealth(c *gin.Context) {
+	health, err := w.manager.ProvidersHealth()
+	if err != nil {
+		w.logger.Err(err).Msgf("error while getting providers health: %v", err)
+		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	healthy, ready, _ := w.manager.Ready()
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "ready": ready, "healthy": healthy, "required": w.config.HealthMinProviders, "data": health})
+}
+
+// Readiness fails while fewer providers than TAG_HEALTH_MIN_PROVIDERS are healthy.
+func (w *WebServer) Readiness(c *gin.Context) {
+	healthy, ready, err := w.manager.Ready()
+	if err != nil {
+		w.logger.Err(err).Msgf("error while checking readiness: %v", err)
+		c.JSON(http.StatusServiceUnavailable, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, &gin.H{"message": "not ready", "healthy": healthy, "required": w.config.HealthMinProviders})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "healthy": healthy, "required": w.config.HealthMinProviders})
+}
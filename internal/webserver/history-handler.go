@@ -0,0 +1,123 @@
+package webserver
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xochilpili/torrent-api-go/internal/archive"
+)
+
+const defaultHistoryLimit = 50
+
+func (w *WebServer) ListArchivedSearches(c *gin.Context) {
+	query, ok := w.archiveQuery(c)
+	if !ok {
+		return
+	}
+	searches, total, err := w.archive.Searches(c.Request.Context(), *query)
+	if err != nil {
+		w.logger.Err(err).Msgf("error while listing archived searches: %v", err)
+		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "total": total, "limit": query.Limit, "offset": query.Offset, "data": searches})
+}
+
+func (w *WebServer) GetArchivedSearch(c *gin.Context) {
+	if !w.archiveEnabled(c) {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": "invalid search id"})
+		return
+	}
+	search, err := w.archive.Search(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(archiveErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": search})
+}
+
+func (w *WebServer) ListArchivedTorrents(c *gin.Context) {
+	query, ok := w.archiveQuery(c)
+	if !ok {
+		return
+	}
+	torrents, total, err := w.archive.Torrents(c.Request.Context(), *query)
+	if err != nil {
+		w.logger.Err(err).Msgf("error while listing archived torrents: %v", err)
+		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "total": total, "limit": query.Limit, "offset": query.Offset, "data": torrents})
+}
+
+// GetArchivedTorrent returns the last known version of a torrent and its seed
+// history without searching the providers again.
+func (w *WebServer) GetArchivedTorrent(c *gin.Context) {
+	if !w.archiveEnabled(c) {
+		return
+	}
+	torrent, err := w.archive.Torrent(c.Request.Context(), c.Param("hash"))
+	if err != nil {
+		c.JSON(archiveErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": torrent})
+}
+
+func (w *WebServer) archiveEnabled(c *gin.Context) bool {
+	if w.archive == nil {
+		c.JSON(http.StatusNotFound, &gin.H{"message": "error", "error": "search history is disabled, set TAG_ARCHIVE_ENABLED to keep it"})
+		return false
+	}
+	return true
+}
+
+func (w *WebServer) archiveQuery(c *gin.Context) (*archive.Query, bool) {
+	if !w.archiveEnabled(c) {
+		return nil, false
+	}
+	query := &archive.Query{
+		Term:     c.Query("term"),
+		Provider: c.Query("provider"),
+		Limit:    defaultHistoryLimit,
+	}
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 {
+			c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": "invalid limit value"})
+			return nil, false
+		}
+		query.Limit = value
+	}
+	if offset := c.Query("offset"); offset != "" {
+		value, err := strconv.Atoi(offset)
+		if err != nil || value < 0 {
+			c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": "invalid offset value"})
+			return nil, false
+		}
+		query.Offset = value
+	}
+	if since := c.Query("since"); since != "" {
+		value, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": "invalid since value, expected RFC3339"})
+			return nil, false
+		}
+		query.Since = value
+	}
+	return query, true
+}
+
+func archiveErrorStatus(err error) int {
+	if errors.Is(err, archive.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -1,11 +1,85 @@
 package webserver
 
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/xochilpili/torrent-api-go/internal/metrics"
+)
+
 func (w *WebServer) loadRoutes() {
 	api := w.ginger.Group("/")
 	api.GET("/ping", w.PingHandler)
+	api.GET("/profiles", w.ListProfiles)
+	api.GET("/metrics", gin.WrapH(metrics.Handler()))
+	// download clients, the watchlist and webhooks act on other systems and
+	// share the admin token
+	download := w.ginger.Group("/download", w.AdminAuth)
+	{
+		download.GET("/clients", w.ListDownloadClients)
+		download.POST("", w.Download)
+	}
+	health := w.ginger.Group("/health")
+	{
+		health.GET("/providers", w.ProvidersHealth)
+		health.GET("/ready", w.Readiness)
+	}
 	search := w.ginger.Group("/search")
 	{
 		search.GET("/:provider/", w.SearchByProvider)
 		search.GET("/all/", w.SearchAll)
+		search.GET("/all/stream", w.StreamSearchAll)
+	}
+	admin := w.ginger.Group("/admin", w.AdminAuth)
+	{
+		admin.GET("/providers", w.ListProviders)
+		admin.GET("/providers/validate", w.ValidateProviders)
+		admin.GET("/providers/:provider", w.GetProvider)
+		admin.PATCH("/providers/:provider", w.UpdateProvider)
+		admin.POST("/providers/:provider/enable", w.EnableProvider)
+		admin.POST("/providers/:provider/disable", w.DisableProvider)
+		admin.DELETE("/providers/:provider/overrides", w.ResetProvider)
+	}
+	watch := w.ginger.Group("/watchlist", w.AdminAuth)
+	{
+		watch.GET("", w.ListWatchlist)
+		watch.POST("", w.AddWatchlistItem)
+		watch.GET("/history", w.WatchlistHistory)
+		watch.GET("/:id", w.GetWatchlistItem)
+		watch.PATCH("/:id", w.UpdateWatchlistItem)
+		watch.DELETE("/:id", w.DeleteWatchlistItem)
+		watch.GET("/:id/history", w.WatchlistHistory)
+		watch.POST("/:id/check", w.CheckWatchlistItem)
+	}
+	history := w.ginger.Group("/history")
+	{
+		history.GET("/searches", w.ListArchivedSearches)
+		history.GET("/searches/:id", w.GetArchivedSearch)
+		history.GET("/torrents", w.ListArchivedTorrents)
+		history.GET("/torrents/:hash", w.GetArchivedTorrent)
+	}
+	hooks := w.ginger.Group("/webhooks", w.AdminAuth)
+	{
+		hooks.GET("/targets", w.ListWebhookTargets)
+		hooks.POST("/targets", w.AddWebhookTarget)
+		hooks.GET("/targets/:id", w.GetWebhookTarget)
+		hooks.PATCH("/targets/:id", w.UpdateWebhookTarget)
+		hooks.DELETE("/targets/:id", w.DeleteWebhookTarget)
+		hooks.POST("/targets/:id/test", w.TestWebhookTarget)
+		hooks.GET("/searches", w.ListSavedSearches)
+		hooks.POST("/searches", w.AddSavedSearch)
+		hooks.GET("/searches/:id", w.GetSavedSearch)
+		hooks.PATCH("/searches/:id", w.UpdateSavedSearch)
+		hooks.DELETE("/searches/:id", w.DeleteSavedSearch)
+		hooks.POST("/searches/:id/run", w.RunSavedSearch)
+		hooks.GET("/deliveries", w.ListWebhookDeliveries)
+	}
+	feed := w.ginger.Group("/feed")
+	{
+		feed.GET("/search/:provider/", w.FeedSearchByProvider)
+		feed.GET("/search/all/", w.FeedSearchAll)
+	}
+	torznab := w.ginger.Group("/torznab")
+	{
+		torznab.GET("/api", w.TorznabAll)
+		torznab.GET("/:provider/api", w.TorznabByProvider)
 	}
 }
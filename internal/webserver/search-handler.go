@@ -1,8 +1,11 @@
 package webserver
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -11,44 +14,56 @@ import (
 )
 
 func (w *WebServer) SearchAll(c *gin.Context) {
-	query := c.Query("term")
-	if query == "" {
-		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": "bad request"})
+	params, err := w.searchParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
 		return
 	}
-	info, _ := parsetorrentname.Parse(query)
-	queryString := url.PathEscape(query)
-	res := c.Query("res")
-	group := c.Query("group")
-
-	params := providers.SearchParams{
-		Query: queryString,
-		Filters: providers.ParamFilters{
-			Title:      info.Title,
-			Resolution: "",
-			Group:      "",
-			Season:     info.Season,
-			Episode:    info.Episode,
-		},
-	}
 
-	if res != "" {
-		params.Filters.Resolution = strings.ToLower(res)
+	w.logger.Info().Msgf("searching %s with filters: %s", params.Query, strings.Join([]string{params.Filters.Resolution, params.Filters.Group}, ","))
+	result, err := w.manager.FetchAllActive(c.Request.Context(), *params)
+	if err != nil {
+		w.logger.Err(err).Msgf("error while fetching torrents: %v", err)
+		c.JSON(searchErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
 	}
+	w.logger.Info().Msgf("resolved %d torrents", len(result.Torrents))
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "total": result.Total, "page": result.Page, "limit": result.Limit, "partial": result.Partial(), "providers": result.Providers, "data": result.Torrents})
+}
 
-	if group != "" {
-		params.Filters.Group = strings.ToLower(group)
+// StreamSearchAll sends torrents as server-sent events while providers find
+// them, followed by per provider done/error events and a ranked summary.
+func (w *WebServer) StreamSearchAll(c *gin.Context) {
+	params, err := w.searchParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
 	}
 
-	w.logger.Info().Msgf("searching %s with filters: %s", queryString, strings.Join([]string{params.Filters.Resolution, params.Filters.Group}, ","))
-	torrents, err := w.manager.FetchAllActive(c.Request.Context(), params)
+	w.logger.Info().Msgf("streaming search %s with filters: %s", params.Query, strings.Join([]string{params.Filters.Resolution, params.Filters.Group}, ","))
+	started := false
+	err = w.manager.StreamAllActive(c.Request.Context(), *params, func(event *providers.StreamEvent) {
+		if !started {
+			started = true
+			c.Header("Content-Type", "text/event-stream")
+			c.Header("Cache-Control", "no-cache")
+			c.Header("Connection", "keep-alive")
+			c.Header("X-Accel-Buffering", "no")
+			c.Status(http.StatusOK)
+		}
+		if event.Event == providers.StreamEventSummary {
+			result := event.Result
+			w.logger.Info().Msgf("resolved %d torrents", len(result.Torrents))
+			c.SSEvent(event.Event, &gin.H{"message": "ok", "total": result.Total, "page": result.Page, "limit": result.Limit, "partial": result.Partial(), "providers": result.Providers, "data": result.Torrents})
+		} else {
+			c.SSEvent(event.Event, event)
+		}
+		c.Writer.Flush()
+	})
 	if err != nil {
-		w.logger.Err(err).Msgf("error while fetching torrents: %v", err)
-		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error", "error": err})
-		return
+		w.logger.Err(err).Msgf("error while streaming torrents: %v", err)
+		c.JSON(searchErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
 	}
-	w.logger.Info().Msgf("resolved %d torrents", len(torrents))
-	c.JSON(http.StatusOK, &gin.H{"message": "ok", "total": len(torrents), "data": torrents})
 }
 
 func (w *WebServer) SearchByProvider(c *gin.Context) {
@@ -58,43 +73,94 @@ func (w *WebServer) SearchByProvider(c *gin.Context) {
 		return
 	}
 
+	params, err := w.searchParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+
+	w.logger.Info().Msgf("searching %s to provider: %s with filters: %s", params.Query, provider, strings.Join([]string{params.Filters.Group, params.Filters.Resolution}, ","))
+	result, err := w.manager.FetchByProvider(c.Request.Context(), provider, *params)
+	if err != nil {
+		w.logger.Err(err).Msgf("error while fetching torrents: %v", err)
+		setRetryAfter(c, err)
+		c.JSON(searchErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	w.logger.Info().Msgf("resolved %d torrents for provider: %s", len(result.Torrents), provider)
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "total": result.Total, "page": result.Page, "limit": result.Limit, "providers": result.Providers, "data": result.Torrents})
+}
+
+func (w *WebServer) searchParams(c *gin.Context) (*providers.SearchParams, error) {
 	query := c.Query("term")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": "bad request"})
-		return
+		return nil, errors.New("bad request")
 	}
 	info, _ := parsetorrentname.Parse(query)
-	queryString := url.PathEscape(query)
-	res := c.Query("res")
-	group := c.Query("group")
 
-	params := providers.SearchParams{
-		Query: queryString,
+	params := &providers.SearchParams{
+		Query: url.PathEscape(query),
 		Filters: providers.ParamFilters{
 			Title:      info.Title,
-			Resolution: "",
-			Group:      "",
+			Resolution: strings.ToLower(c.Query("res")),
+			Group:      strings.ToLower(c.Query("group")),
 			Season:     info.Season,
 			Episode:    info.Episode,
 		},
+		Profile: c.Query("profile"),
+		Sort:    c.Query("sort"),
+		Order:   c.Query("order"),
+	}
+
+	if page := c.Query("page"); page != "" {
+		value, err := strconv.Atoi(page)
+		if err != nil || value < 1 {
+			return nil, errors.New("invalid page value")
+		}
+		params.Page = value
 	}
 
-	if res != "" {
-		params.Filters.Resolution = res
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 0 {
+			return nil, errors.New("invalid limit value")
+		}
+		params.Limit = value
 	}
 
-	if group != "" {
-		params.Filters.Group = group
+	if dedupe := c.Query("dedupe"); dedupe != "" {
+		enabled, err := strconv.ParseBool(dedupe)
+		if err != nil {
+			return nil, errors.New("invalid dedupe value")
+		}
+		params.KeepDuplicates = !enabled
 	}
+	return params, nil
+}
 
-	w.logger.Info().Msgf("searching %s to provider: %s with filters: %s", queryString, provider, strings.Join([]string{params.Filters.Group, params.Filters.Resolution}, ","))
-	torrents, err := w.manager.FetchByProvider(c.Request.Context(), provider, params)
+func (w *WebServer) ListProfiles(c *gin.Context) {
+	profiles := w.manager.Profiles()
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "default": profiles.Default, "data": profiles.Profiles})
+}
 
-	if err != nil {
-		w.logger.Err(err).Msgf("error while fetching torrents: %v", err)
-		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error", "error": err})
-		return
+func searchErrorStatus(err error) int {
+	if errors.Is(err, providers.ErrUnknownProvider) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, providers.ErrProviderUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+	if errors.Is(err, providers.ErrUnknownProfile) || errors.Is(err, providers.ErrInvalidSort) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// setRetryAfter tells clients when a provider skipped by its circuit breaker
+// is searched again.
+func setRetryAfter(c *gin.Context, err error) {
+	var unavailable *providers.ProviderUnavailableError
+	if errors.As(err, &unavailable) && unavailable.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(unavailable.RetryAfter.Seconds()))))
 	}
-	w.logger.Info().Msgf("resolved %d torrents for provider: %s", len(torrents), provider)
-	c.JSON(http.StatusOK, &gin.H{"message": "ok", "total": len(torrents), "data": torrents})
 }
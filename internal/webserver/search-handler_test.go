@@ -0,0 +1,36 @@
+package webserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xochilpili/torrent-api-go/internal/providers"
+)
+
+func TestUnavailableProviderStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		err        error
+		status     int
+		retryAfter string
+	}{
+		{err: &providers.ProviderUnavailableError{Status: &providers.ProviderStatus{Provider: "yts", Status: providers.ProviderStatusSkipped}, RetryAfter: 90*time.Second + time.Millisecond}, status: http.StatusServiceUnavailable, retryAfter: "91"},
+		{err: &providers.ProviderUnavailableError{Status: &providers.ProviderStatus{Provider: "yts", Status: providers.ProviderStatusTimeout}}, status: http.StatusServiceUnavailable},
+		{err: fmt.Errorf("%w: nope", providers.ErrUnknownProvider), status: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		setRetryAfter(c, test.err)
+		if status := searchErrorStatus(test.err); status != test.status {
+			t.Errorf("%v: expected status %d, got %d", test.err, test.status, status)
+		}
+		if retryAfter := recorder.Header().Get("Retry-After"); retryAfter != test.retryAfter {
+			t.Errorf("%v: expected Retry-After %q, got %q", test.err, test.retryAfter, retryAfter)
+		}
+	}
+}
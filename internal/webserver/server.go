@@ -1,46 +1,176 @@
 package webserver
 
 import (
+	"context"
+	"fmt"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 
 	ginlogger "github.com/gin-contrib/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
+	"github.com/xochilpili/torrent-api-go/internal/archive"
 	"github.com/xochilpili/torrent-api-go/internal/config"
+	"github.com/xochilpili/torrent-api-go/internal/downloader"
+	"github.com/xochilpili/torrent-api-go/internal/metrics"
 	"github.com/xochilpili/torrent-api-go/internal/providers"
+	"github.com/xochilpili/torrent-api-go/internal/watchlist"
+	"github.com/xochilpili/torrent-api-go/internal/webhooks"
 )
 
 type WebServer struct {
-	config  *config.Config
-	logger  *zerolog.Logger
-	Web     *http.Server
-	ginger  *gin.Engine
-	manager *providers.TorrentManager
+	config     *config.Config
+	logger     *zerolog.Logger
+	Web        *http.Server
+	ginger     *gin.Engine
+	manager    *providers.TorrentManager
+	downloader *downloader.Downloader
+	watchlist  *watchlist.Watchlist
+	archive    *archive.Archive
+	webhooks   *webhooks.Monitor
+	workers    sync.WaitGroup
+	// proxies trusted to tell the scheme the client used
+	trustedProxies []*net.IPNet
 }
 
-func New(config *config.Config, logger *zerolog.Logger) *WebServer {
+func New(config *config.Config, logger *zerolog.Logger) (*WebServer, error) {
 	ginger := gin.New()
 	ginger.Use(gin.Recovery())
 	ginger.Use(ginlogger.SetLogger(
-		ginlogger.WithSkipPath([]string{"/ping"}),
+		ginlogger.WithSkipPath([]string{"/ping", "/metrics", "/health/ready"}),
 		ginlogger.WithLogger(func(ctx *gin.Context, l zerolog.Logger) zerolog.Logger {
 			return logger.Output(gin.DefaultWriter).With().Logger()
 		}),
 	))
+	ginger.Use(metrics.GinMiddleware())
+	trustedProxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	if err := ginger.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, err
+	}
 
 	httpSrv := &http.Server{
 		Addr:    config.Host + ":" + config.Port,
 		Handler: ginger,
 	}
 
-	manager := providers.NewTorrentManager(logger)
+	manager, err := providers.NewTorrentManager(config, logger)
+	if err != nil {
+		return nil, err
+	}
+	downloads, err := downloader.New(config, logger)
+	if err != nil {
+		return nil, err
+	}
+	store, err := watchlist.NewStore(config.WatchlistFile)
+	if err != nil {
+		return nil, err
+	}
+	hooks, err := webhooks.NewStore(config.WebhooksFile)
+	if err != nil {
+		return nil, err
+	}
+	dispatcher := webhooks.NewDispatcher(hooks, logger, config.WebhooksTimeout, config.WebhooksRetries, config.WebhooksBackoff)
 	srv := &WebServer{
-		config:  config,
-		logger:  logger,
-		Web:     httpSrv,
-		ginger:  ginger,
-		manager: manager,
+		config:     config,
+		logger:     logger,
+		Web:        httpSrv,
+		ginger:     ginger,
+		manager:    manager,
+		downloader: downloads,
+		watchlist:  watchlist.New(store, manager, downloads, logger),
+		webhooks:   webhooks.NewMonitor(hooks, manager, dispatcher, logger),
+
+		trustedProxies: trustedProxies,
+	}
+	if config.ArchiveEnabled {
+		srv.archive, err = archive.Open(config.ArchiveFile, logger)
+		if err != nil {
+			return nil, err
+		}
+		manager.AddObserver(srv.archive)
 	}
 	srv.loadRoutes()
-	return srv
+	return srv, nil
+}
+
+// StartWorkers runs the background jobs of the server until ctx is done,
+// WaitWorkers returns once they all stopped.
+func (w *WebServer) StartWorkers(ctx context.Context) {
+	w.spawn(func() { w.manager.WatchProviders(ctx) })
+	if w.config.HealthEnabled {
+		w.spawn(func() { w.manager.RunHealthChecks(ctx) })
+	}
+	if w.config.WatchlistEnabled {
+		w.spawn(func() { w.watchlist.Watch(ctx, w.config.WatchlistInterval) })
+	}
+	// saved searches run by hand queue deliveries even without the interval
+	w.spawn(func() { w.webhooks.Dispatcher().Serve(ctx) })
+	if w.config.WebhooksEnabled {
+		w.spawn(func() { w.webhooks.Watch(ctx, w.config.WebhooksInterval) })
+	}
+}
+
+func (w *WebServer) spawn(job func()) {
+	w.workers.Add(1)
+	go func() {
+		defer w.workers.Done()
+		job()
+	}()
+}
+
+func (w *WebServer) WaitWorkers() {
+	w.workers.Wait()
+}
+
+// Close releases what the server holds once it stopped serving requests and
+// its workers are done.
+func (w *WebServer) Close() error {
+	if w.archive != nil {
+		return w.archive.Close()
+	}
+	return nil
+}
+
+// parseTrustedProxies accepts ips and cidrs, a plain ip trusts that address only.
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func (w *WebServer) trustedProxy(c *gin.Context) bool {
+	ip := net.ParseIP(c.RemoteIP())
+	if ip == nil {
+		return false
+	}
+	for _, network := range w.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,380 @@
+package webserver
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	parsetorrentname "github.com/xochilpili/go-parse-torrent-name"
+	"github.com/xochilpili/torrent-api-go/internal/providers"
+)
+
+const (
+	torznabCategoryMovies   = 2000
+	torznabCategoryMoviesHD = 2040
+	torznabCategoryMovieUHD = 2045
+	torznabCategoryTV       = 5000
+	torznabCategoryTVHD     = 5040
+	torznabCategoryTVUHD    = 5045
+	torznabMaxLimit         = 100
+)
+
+type torznabError struct {
+	XMLName     xml.Name `xml:"error"`
+	Code        int      `xml:"code,attr"`
+	Description string   `xml:"description,attr"`
+}
+
+type torznabCaps struct {
+	XMLName xml.Name `xml:"caps"`
+	Server  struct {
+		Title string `xml:"title,attr"`
+	} `xml:"server"`
+	Limits struct {
+		Max     int `xml:"max,attr"`
+		Default int `xml:"default,attr"`
+	} `xml:"limits"`
+	Searching struct {
+		Search      torznabSearchCap `xml:"search"`
+		TvSearch    torznabSearchCap `xml:"tv-search"`
+		MovieSearch torznabSearchCap `xml:"movie-search"`
+	} `xml:"searching"`
+	Categories []torznabCategory `xml:"categories>category"`
+}
+
+type torznabSearchCap struct {
+	Available       string `xml:"available,attr"`
+	SupportedParams string `xml:"supportedParams,attr"`
+}
+
+type torznabCategory struct {
+	Id      int                  `xml:"id,attr"`
+	Name    string               `xml:"name,attr"`
+	Subcats []torznabSubcategory `xml:"subcat"`
+}
+
+type torznabSubcategory struct {
+	Id   int    `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+}
+
+type torznabRss struct {
+	XMLName   xml.Name       `xml:"rss"`
+	Version   string         `xml:"version,attr"`
+	AtomNS    string         `xml:"xmlns:atom,attr"`
+	TorznabNS string         `xml:"xmlns:torznab,attr"`
+	Channel   torznabChannel `xml:"channel"`
+}
+
+type torznabChannel struct {
+	Title       string        `xml:"title"`
+	Description string        `xml:"description"`
+	Link        string        `xml:"link"`
+	Items       []torznabItem `xml:"item"`
+}
+
+type torznabItem struct {
+	Title      string           `xml:"title"`
+	Guid       string           `xml:"guid"`
+	Link       string           `xml:"link"`
+	PubDate    string           `xml:"pubDate"`
+	Size       int64            `xml:"size"`
+	Categories []int            `xml:"category"`
+	Enclosure  torznabEnclosure `xml:"enclosure"`
+	Attrs      []torznabAttr    `xml:"torznab:attr"`
+}
+
+type torznabEnclosure struct {
+	Url    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type torznabAttr struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+func (w *WebServer) TorznabAll(c *gin.Context) {
+	w.torznab(c, "")
+}
+
+func (w *WebServer) TorznabByProvider(c *gin.Context) {
+	provider := c.Param("provider")
+	if provider == "" {
+		w.torznabError(c, 200, "missing provider")
+		return
+	}
+	w.torznab(c, provider)
+}
+
+func (w *WebServer) torznab(c *gin.Context, provider string) {
+	switch c.Query("t") {
+	case "caps":
+		caps, err := w.manager.Capabilities(provider)
+		if err != nil {
+			w.logger.Err(err).Msgf("error while loading capabilities: %v", err)
+			w.torznabError(c, 300, err.Error())
+			return
+		}
+		w.renderXML(c, http.StatusOK, w.torznabCaps(provider, caps))
+	case "search", "tvsearch", "movie":
+		w.torznabSearch(c, provider)
+	case "":
+		w.torznabError(c, 200, "missing parameter t")
+	default:
+		w.torznabError(c, 202, fmt.Sprintf("no such function: %s", c.Query("t")))
+	}
+}
+
+func (w *WebServer) torznabCaps(provider string, providerCaps providers.Capabilities) *torznabCaps {
+	caps := &torznabCaps{}
+	caps.Server.Title = "torrent-api"
+	if provider != "" {
+		caps.Server.Title = fmt.Sprintf("torrent-api (%s)", provider)
+	}
+	caps.Limits.Max = torznabMaxLimit
+	caps.Limits.Default = torznabMaxLimit
+	caps.Searching.Search = torznabSearchCap{Available: "yes", SupportedParams: "q"}
+	caps.Searching.TvSearch = torznabSearchCap{Available: "no", SupportedParams: "q,season,ep"}
+	caps.Searching.MovieSearch = torznabSearchCap{Available: "no", SupportedParams: "q"}
+	if providerCaps.Series {
+		caps.Searching.TvSearch.Available = "yes"
+		caps.Categories = append(caps.Categories, torznabCategory{
+			Id:   torznabCategoryTV,
+			Name: "TV",
+			Subcats: []torznabSubcategory{
+				{Id: torznabCategoryTVHD, Name: "TV/HD"},
+				{Id: torznabCategoryTVUHD, Name: "TV/UHD"},
+			},
+		})
+	}
+	if providerCaps.Movies {
+		caps.Searching.MovieSearch.Available = "yes"
+		if providerCaps.ImdbSearch {
+			caps.Searching.MovieSearch.SupportedParams = "q,imdbid"
+		}
+		caps.Categories = append(caps.Categories, torznabCategory{
+			Id:   torznabCategoryMovies,
+			Name: "Movies",
+			Subcats: []torznabSubcategory{
+				{Id: torznabCategoryMoviesHD, Name: "Movies/HD"},
+				{Id: torznabCategoryMovieUHD, Name: "Movies/UHD"},
+			},
+		})
+	}
+	return caps
+}
+
+func (w *WebServer) torznabSearch(c *gin.Context, provider string) {
+	query := strings.TrimSpace(c.Query("q"))
+	title := query
+	season, _ := strconv.Atoi(c.Query("season"))
+	episode, _ := strconv.Atoi(c.Query("ep"))
+
+	switch c.Query("t") {
+	case "tvsearch":
+		if query != "" && season > 0 {
+			query = fmt.Sprintf("%s S%02d", query, season)
+			if episode > 0 {
+				query = fmt.Sprintf("%sE%02d", query, episode)
+			}
+		}
+	case "movie":
+		imdb := strings.TrimPrefix(strings.ToLower(c.Query("imdbid")), "tt")
+		if query == "" && imdb != "" {
+			query = "tt" + imdb
+			title = ""
+		}
+	}
+
+	feed := w.torznabFeed(c, provider)
+	if query == "" {
+		// torznab clients probe indexers with empty queries, answer with an empty feed
+		w.renderXML(c, http.StatusOK, feed)
+		return
+	}
+
+	params := providers.SearchParams{
+		Query: url.PathEscape(query),
+		Filters: providers.ParamFilters{
+			Season:  season,
+			Episode: episode,
+		},
+		Profile: c.Query("profile"),
+	}
+	if title != "" {
+		info, _ := parsetorrentname.Parse(title)
+		params.Filters.Title = info.Title
+	}
+
+	w.logger.Info().Msgf("torznab searching %s to provider: %s", params.Query, provider)
+	var result *providers.SearchResult
+	var err error
+	if provider == "" {
+		result, err = w.manager.FetchAllActive(c.Request.Context(), params)
+	} else {
+		result, err = w.manager.FetchByProvider(c.Request.Context(), provider, params)
+	}
+	if err != nil {
+		w.logger.Err(err).Msgf("error while fetching torrents: %v", err)
+		if errors.Is(err, providers.ErrProviderUnavailable) {
+			// indexer clients back off on a 503 with Retry-After
+			setRetryAfter(c, err)
+			w.renderXML(c, http.StatusServiceUnavailable, &torznabError{Code: 300, Description: err.Error()})
+			return
+		}
+		w.torznabError(c, 300, err.Error())
+		return
+	}
+
+	categories := parseTorznabCategories(c.Query("cat"))
+	pubDate := time.Now().Format(time.RFC1123Z)
+	for _, torrent := range result.Torrents {
+		item := newTorznabItem(torrent, pubDate)
+		if !torznabMatchesCategories(item.Categories, categories) {
+			continue
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 || limit > torznabMaxLimit {
+		limit = torznabMaxLimit
+	}
+	if offset < 0 || offset > len(feed.Channel.Items) {
+		offset = len(feed.Channel.Items)
+	}
+	feed.Channel.Items = feed.Channel.Items[offset:]
+	if len(feed.Channel.Items) > limit {
+		feed.Channel.Items = feed.Channel.Items[:limit]
+	}
+
+	w.logger.Info().Msgf("torznab resolved %d torrents", len(feed.Channel.Items))
+	w.renderXML(c, http.StatusOK, feed)
+}
+
+func (w *WebServer) torznabFeed(c *gin.Context, provider string) *torznabRss {
+	title := "torrent-api"
+	if provider != "" {
+		title = fmt.Sprintf("torrent-api (%s)", provider)
+	}
+	return &torznabRss{
+		Version:   "2.0",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		TorznabNS: "http://torznab.com/schemas/2015/feed",
+		Channel: torznabChannel{
+			Title:       title,
+			Description: "torrent-api torznab feed",
+			Link:        c.Request.URL.Path,
+		},
+	}
+}
+
+func newTorznabItem(torrent *providers.Torrent, pubDate string) torznabItem {
+	size := torrent.SizeBytes
+	infoHash := torrent.InfoHash
+	guid := infoHash
+	if guid == "" {
+		guid = torrent.Magnet
+	}
+	categories := torznabCategories(torrent)
+
+	attrs := []torznabAttr{
+		{Name: "category", Value: strconv.Itoa(categories[0])},
+		{Name: "size", Value: strconv.FormatInt(size, 10)},
+		{Name: "seeders", Value: strconv.Itoa(torrent.Seeds)},
+		{Name: "peers", Value: strconv.Itoa(torrent.Seeds + torrent.Peers)},
+		{Name: "magneturl", Value: torrent.Magnet},
+	}
+	if infoHash != "" {
+		attrs = append(attrs, torznabAttr{Name: "infohash", Value: infoHash})
+	}
+	if torrent.Season != 0 {
+		attrs = append(attrs, torznabAttr{Name: "season", Value: strconv.Itoa(torrent.Season)})
+	}
+	if torrent.Episode != 0 {
+		attrs = append(attrs, torznabAttr{Name: "episode", Value: strconv.Itoa(torrent.Episode)})
+	}
+
+	return torznabItem{
+		Title:      torrent.OriginalTitle,
+		Guid:       guid,
+		Link:       torrent.Magnet,
+		PubDate:    pubDate,
+		Size:       size,
+		Categories: categories,
+		Enclosure: torznabEnclosure{
+			Url:    torrent.Magnet,
+			Length: size,
+			Type:   "application/x-bittorrent;x-scheme-handler/magnet",
+		},
+		Attrs: attrs,
+	}
+}
+
+func torznabCategories(torrent *providers.Torrent) []int {
+	parent, hd, uhd := torznabCategoryMovies, torznabCategoryMoviesHD, torznabCategoryMovieUHD
+	if torrent.Type == "serie" {
+		parent, hd, uhd = torznabCategoryTV, torznabCategoryTVHD, torznabCategoryTVUHD
+	}
+	switch strings.ToLower(torrent.Resolution) {
+	case "2160p", "4k":
+		return []int{parent, uhd}
+	case "720p", "1080p":
+		return []int{parent, hd}
+	}
+	return []int{parent}
+}
+
+func parseTorznabCategories(cat string) []int {
+	var categories []int
+	for _, str := range strings.Split(cat, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(str))
+		if err != nil {
+			continue
+		}
+		categories = append(categories, id)
+	}
+	return categories
+}
+
+func torznabMatchesCategories(itemCategories []int, categories []int) bool {
+	if len(categories) == 0 {
+		return true
+	}
+	for _, wanted := range categories {
+		for _, category := range itemCategories {
+			// a parent category (2000, 5000) matches all of its subcategories
+			if category == wanted || (wanted%1000 == 0 && category/1000 == wanted/1000) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (w *WebServer) torznabError(c *gin.Context, code int, description string) {
+	w.renderXML(c, http.StatusOK, &torznabError{Code: code, Description: description})
+}
+
+func (w *WebServer) renderXML(c *gin.Context, status int, data interface{}) {
+	w.renderXMLAs(c, status, data, "application/xml; charset=utf-8")
+}
+
+func (w *WebServer) renderXMLAs(c *gin.Context, status int, data interface{}, contentType string) {
+	body, err := xml.Marshal(data)
+	if err != nil {
+		w.logger.Err(err).Msgf("error while rendering xml: %v", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(status, contentType, append([]byte(xml.Header), body...))
+}
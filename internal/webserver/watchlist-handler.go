@@ -0,0 +1,171 @@
+package webserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xochilpili/torrent-api-go/internal/downloader"
+	"github.com/xochilpili/torrent-api-go/internal/watchlist"
+)
+
+// watchlistRequest uses pointers so a PATCH only changes the sent fields.
+type watchlistRequest struct {
+	Title      *string `json:"title"`
+	Season     *int    `json:"season"`
+	Episode    *int    `json:"episode"`
+	Resolution *string `json:"resolution"`
+	Group      *string `json:"group"`
+	Profile    *string `json:"profile"`
+	Client     *string `json:"client"`
+	Category   *string `json:"category"`
+	SavePath   *string `json:"save_path"`
+	Paused     *bool   `json:"paused"`
+	Enabled    *bool   `json:"enabled"`
+}
+
+func (r *watchlistRequest) apply(item *watchlist.Item) {
+	setString := func(target *string, value *string) {
+		if value != nil {
+			*target = strings.TrimSpace(*value)
+		}
+	}
+	setString(&item.Title, r.Title)
+	setString(&item.Resolution, r.Resolution)
+	setString(&item.Group, r.Group)
+	setString(&item.Profile, r.Profile)
+	setString(&item.Client, r.Client)
+	setString(&item.Category, r.Category)
+	setString(&item.SavePath, r.SavePath)
+	if r.Season != nil {
+		item.Season = *r.Season
+	}
+	if r.Episode != nil {
+		item.Episode = *r.Episode
+	}
+	if r.Paused != nil {
+		item.Paused = *r.Paused
+	}
+	if r.Enabled != nil {
+		item.Enabled = *r.Enabled
+	}
+}
+
+func (w *WebServer) validateWatchlistItem(item *watchlist.Item) error {
+	if item.Title == "" {
+		return errors.New("title is required")
+	}
+	if item.Season < 1 || item.Episode < 1 {
+		return errors.New("season and episode must be greater than 0")
+	}
+	if item.Client != "" && !slices.Contains(w.downloader.Clients(), item.Client) {
+		return fmt.Errorf("%w: %s", downloader.ErrUnknownClient, item.Client)
+	}
+	return nil
+}
+
+func (w *WebServer) ListWatchlist(c *gin.Context) {
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": w.watchlist.Store().Items()})
+}
+
+func (w *WebServer) GetWatchlistItem(c *gin.Context) {
+	item, err := w.watchlist.Store().Get(c.Param("id"))
+	if err != nil {
+		c.JSON(watchlistErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": item})
+}
+
+func (w *WebServer) AddWatchlistItem(c *gin.Context) {
+	var body watchlistRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	item := &watchlist.Item{Season: 1, Episode: 1, Enabled: true}
+	body.apply(item)
+	if err := w.validateWatchlistItem(item); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+
+	item, err := w.watchlist.Store().Add(item)
+	if err != nil {
+		w.logger.Err(err).Msgf("error while saving watchlist item: %v", err)
+		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, &gin.H{"message": "ok", "data": item})
+}
+
+func (w *WebServer) UpdateWatchlistItem(c *gin.Context) {
+	var body watchlistRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	id := c.Param("id")
+	current, err := w.watchlist.Store().Get(id)
+	if err != nil {
+		c.JSON(watchlistErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	body.apply(current)
+	if err := w.validateWatchlistItem(current); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+
+	item, err := w.watchlist.Store().Update(id, body.apply)
+	if err != nil {
+		w.logger.Err(err).Msgf("error while updating watchlist item %s: %v", id, err)
+		c.JSON(watchlistErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": item})
+}
+
+func (w *WebServer) DeleteWatchlistItem(c *gin.Context) {
+	if err := w.watchlist.Store().Delete(c.Param("id")); err != nil {
+		c.JSON(watchlistErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok"})
+}
+
+// CheckWatchlistItem runs the search of an item right away instead of
+// waiting for the scheduler.
+func (w *WebServer) CheckWatchlistItem(c *gin.Context) {
+	grab, err := w.watchlist.Check(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		w.logger.Err(err).Msgf("error while checking watchlist item: %v", err)
+		c.JSON(watchlistErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "grabbed": grab != nil, "data": grab})
+}
+
+func (w *WebServer) WatchlistHistory(c *gin.Context) {
+	id := c.Param("id")
+	if id != "" {
+		if _, err := w.watchlist.Store().Get(id); err != nil {
+			c.JSON(watchlistErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": w.watchlist.Store().History(id)})
+}
+
+func watchlistErrorStatus(err error) int {
+	if errors.Is(err, watchlist.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, downloader.ErrUnknownClient) || errors.Is(err, downloader.ErrNoClient) {
+		return http.StatusBadRequest
+	}
+	return searchErrorStatus(err)
+}
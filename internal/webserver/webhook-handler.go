@@ -0,0 +1,316 @@
+package webserver
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xochilpili/torrent-api-go/internal/providers"
+	"github.com/xochilpili/torrent-api-go/internal/webhooks"
+)
+
+// webhookTargetRequest uses pointers so a PATCH only changes the sent fields.
+type webhookTargetRequest struct {
+	Name     *string            `json:"name"`
+	Url      *string            `json:"url"`
+	Secret   *string            `json:"secret"`
+	Template *string            `json:"template"`
+	Headers  *map[string]string `json:"headers"`
+	Enabled  *bool              `json:"enabled"`
+}
+
+func (r *webhookTargetRequest) apply(target *webhooks.Target) {
+	if r.Name != nil {
+		target.Name = strings.TrimSpace(*r.Name)
+	}
+	if r.Url != nil {
+		target.Url = strings.TrimSpace(*r.Url)
+	}
+	// redacted values sent back from a GET keep what is stored
+	if r.Secret != nil && *r.Secret != webhooks.RedactedValue {
+		target.Secret = *r.Secret
+	}
+	if r.Template != nil {
+		target.Template = *r.Template
+	}
+	if r.Headers != nil {
+		headers := make(map[string]string, len(*r.Headers))
+		for key, value := range *r.Headers {
+			if stored, ok := target.Headers[key]; ok && value == webhooks.RedactedValue {
+				value = stored
+			}
+			headers[key] = value
+		}
+		target.Headers = headers
+	}
+	if r.Enabled != nil {
+		target.Enabled = *r.Enabled
+	}
+}
+
+func validateWebhookTarget(target *webhooks.Target) error {
+	if target.Name == "" {
+		return errors.New("name is required")
+	}
+	parsed, err := url.Parse(target.Url)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("url must be an absolute http or https url")
+	}
+	_, err = webhooks.Render(target.Template, webhooks.SamplePayload())
+	return err
+}
+
+type savedSearchRequest struct {
+	Name       *string   `json:"name"`
+	Term       *string   `json:"term"`
+	Provider   *string   `json:"provider"`
+	Resolution *string   `json:"resolution"`
+	Group      *string   `json:"group"`
+	Profile    *string   `json:"profile"`
+	Targets    *[]string `json:"targets"`
+	Enabled    *bool     `json:"enabled"`
+}
+
+func (r *savedSearchRequest) apply(search *webhooks.SavedSearch) {
+	setString := func(target *string, value *string) {
+		if value != nil {
+			*target = strings.TrimSpace(*value)
+		}
+	}
+	setString(&search.Name, r.Name)
+	setString(&search.Term, r.Term)
+	setString(&search.Provider, r.Provider)
+	setString(&search.Resolution, r.Resolution)
+	setString(&search.Group, r.Group)
+	setString(&search.Profile, r.Profile)
+	if r.Targets != nil {
+		search.Targets = *r.Targets
+	}
+	if r.Enabled != nil {
+		search.Enabled = *r.Enabled
+	}
+	if search.Name == "" {
+		search.Name = search.Term
+	}
+}
+
+func (w *WebServer) validateSavedSearch(search *webhooks.SavedSearch) error {
+	if search.Term == "" {
+		return errors.New("term is required")
+	}
+	if search.Provider != "" {
+		if _, err := w.manager.GetProvider(search.Provider); err != nil {
+			return err
+		}
+	}
+	if search.Profile != "" {
+		if _, err := w.manager.Profile(search.Profile); err != nil {
+			return err
+		}
+	}
+	for _, id := range search.Targets {
+		if _, err := w.webhooks.Store().Target(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *WebServer) ListWebhookTargets(c *gin.Context) {
+	targets := []*webhooks.Target{}
+	for _, target := range w.webhooks.Store().Targets() {
+		targets = append(targets, target.Redacted())
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": targets})
+}
+
+func (w *WebServer) GetWebhookTarget(c *gin.Context) {
+	target, err := w.webhooks.Store().Target(c.Param("id"))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": target.Redacted()})
+}
+
+func (w *WebServer) AddWebhookTarget(c *gin.Context) {
+	var body webhookTargetRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	target := &webhooks.Target{Enabled: true}
+	body.apply(target)
+	if err := validateWebhookTarget(target); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+
+	target, err := w.webhooks.Store().AddTarget(target)
+	if err != nil {
+		w.logger.Err(err).Msgf("error while saving webhook target: %v", err)
+		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, &gin.H{"message": "ok", "data": target.Redacted()})
+}
+
+func (w *WebServer) UpdateWebhookTarget(c *gin.Context) {
+	var body webhookTargetRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	id := c.Param("id")
+	current, err := w.webhooks.Store().Target(id)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	body.apply(current)
+	if err := validateWebhookTarget(current); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+
+	target, err := w.webhooks.Store().UpdateTarget(id, body.apply)
+	if err != nil {
+		w.logger.Err(err).Msgf("error while updating webhook target %s: %v", id, err)
+		c.JSON(webhookErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": target.Redacted()})
+}
+
+func (w *WebServer) DeleteWebhookTarget(c *gin.Context) {
+	if err := w.webhooks.Store().DeleteTarget(c.Param("id")); err != nil {
+		c.JSON(webhookErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok"})
+}
+
+// TestWebhookTarget sends a sample release to the target and waits for the
+// outcome, retries included.
+func (w *WebServer) TestWebhookTarget(c *gin.Context) {
+	target, err := w.webhooks.Store().Target(c.Param("id"))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	delivery := w.webhooks.Dispatcher().Deliver(c.Request.Context(), target, webhooks.SamplePayload())
+	if delivery.Status != webhooks.DeliveryDelivered {
+		c.JSON(http.StatusBadGateway, &gin.H{"message": "error", "error": delivery.Error, "data": delivery})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": delivery})
+}
+
+func (w *WebServer) ListSavedSearches(c *gin.Context) {
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": w.webhooks.Store().Searches()})
+}
+
+func (w *WebServer) GetSavedSearch(c *gin.Context) {
+	search, err := w.webhooks.Store().Search(c.Param("id"))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": search})
+}
+
+func (w *WebServer) AddSavedSearch(c *gin.Context) {
+	var body savedSearchRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	search := &webhooks.SavedSearch{Targets: []string{}, Enabled: true}
+	body.apply(search)
+	if err := w.validateSavedSearch(search); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+
+	search, err := w.webhooks.Store().AddSearch(search)
+	if err != nil {
+		w.logger.Err(err).Msgf("error while saving saved search: %v", err)
+		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, &gin.H{"message": "ok", "data": search})
+}
+
+func (w *WebServer) UpdateSavedSearch(c *gin.Context) {
+	var body savedSearchRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	id := c.Param("id")
+	current, err := w.webhooks.Store().Search(id)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	body.apply(current)
+	if err := w.validateSavedSearch(current); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+
+	search, err := w.webhooks.Store().UpdateSearch(id, body.apply)
+	if err != nil {
+		w.logger.Err(err).Msgf("error while updating saved search %s: %v", id, err)
+		c.JSON(webhookErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": search})
+}
+
+func (w *WebServer) DeleteSavedSearch(c *gin.Context) {
+	if err := w.webhooks.Store().DeleteSearch(c.Param("id")); err != nil {
+		c.JSON(webhookErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok"})
+}
+
+// RunSavedSearch runs a saved search right away, new releases are queued for
+// delivery like on a scheduled run.
+func (w *WebServer) RunSavedSearch(c *gin.Context) {
+	fresh, err := w.webhooks.Run(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		w.logger.Err(err).Msgf("error while running saved search: %v", err)
+		c.JSON(webhookErrorStatus(err), &gin.H{"message": "error", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "total": len(fresh), "data": fresh})
+}
+
+func (w *WebServer) ListWebhookDeliveries(c *gin.Context) {
+	limit := 100
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, &gin.H{"message": "error", "error": "invalid limit value"})
+			return
+		}
+		limit = parsed
+	}
+	deliveries := w.webhooks.Store().Deliveries(c.Query("target"), c.Query("search"), c.Query("status"), limit)
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": deliveries})
+}
+
+func webhookErrorStatus(err error) int {
+	if errors.Is(err, webhooks.ErrNotFound) || errors.Is(err, providers.ErrUnknownProvider) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, webhooks.ErrInvalidTemplate) {
+		return http.StatusBadRequest
+	}
+	return searchErrorStatus(err)
+}